@@ -0,0 +1,25 @@
+package bitflux
+
+import "errors"
+
+var (
+	// ErrPadOverrun is reported by PadTo when the stream is already past the requested offset.
+	ErrPadOverrun = errors.New("bitflux: position is past pad offset")
+	// ErrBadPadding is reported when a decoder verifying padding skips a non-zero byte.
+	ErrBadPadding = errors.New("bitflux: non-zero padding")
+)
+
+// alignPad returns the number of bytes needed to advance pos to a multiple of n.
+func alignPad(pos int64, n int) int64 {
+	if n <= 1 {
+		return 0
+	}
+	r := pos % int64(n)
+	if r < 0 {
+		r += int64(n)
+	}
+	if r == 0 {
+		return 0
+	}
+	return int64(n) - r
+}
@@ -0,0 +1,89 @@
+package bitflux
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncAlign(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncLE(&buf)
+	enc.U8(0x01)
+	enc.Align(4)
+	enc.U16(0x0302)
+	enc.Align(4)
+	enc.Align(4) // already aligned
+	enc.Fill = 0xFF
+	enc.U8(0x04)
+	enc.Align(8)
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	expected := []byte{0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x00, 0x00, 0x04, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("got=% x, want=% x", buf.Bytes(), expected)
+	}
+}
+
+func TestEncPadToBase(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncBE(&buf)
+	enc.U8(0xAA) // header outside the structure
+	enc.MarkBase()
+	enc.U16(0x0102)
+	enc.Align(4)
+	enc.PadTo(6)
+	enc.U8(0x03)
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	expected := []byte{0xAA, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x03}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("got=% x, want=% x", buf.Bytes(), expected)
+	}
+
+	enc.PadTo(2)
+	if enc.Err != ErrPadOverrun {
+		t.Fatalf("expected ErrPadOverrun, got %v", enc.Err)
+	}
+}
+
+func TestDecAlign(t *testing.T) {
+	data := []byte{0xAA, 0x01, 0x00, 0x00, 0x00, 0x02, 0x03, 0x00, 0x00, 0x00, 0x04}
+	dec := NewDecLE(bytes.NewReader(data))
+	dec.ZeroPad = true
+	dec.U8()
+	dec.MarkBase()
+	if v := dec.U8(); v != 0x01 {
+		t.Fatalf("U8: got=%#x, want=0x01", v)
+	}
+	dec.Align(4)
+	if v := dec.U16(); v != 0x0302 {
+		t.Fatalf("U16: got=%#x, want=0x0302", v)
+	}
+	dec.PadTo(9)
+	if v := dec.U8(); v != 0x04 {
+		t.Fatalf("U8: got=%#x, want=0x04", v)
+	}
+	if dec.Err != nil {
+		t.Fatalf("unexpected error: %v", dec.Err)
+	}
+}
+
+func TestDecZeroPad(t *testing.T) {
+	data := []byte{0x01, 0x00, 0x7F, 0x00, 0x02}
+	dec := NewDecBE(bytes.NewReader(data))
+	dec.U8()
+	dec.Align(4)
+	if dec.Err != nil {
+		t.Fatalf("unexpected error without ZeroPad: %v", dec.Err)
+	}
+
+	dec = NewDecBE(bytes.NewReader(data))
+	dec.ZeroPad = true
+	dec.U8()
+	dec.Align(4)
+	if dec.Err != ErrBadPadding {
+		t.Fatalf("expected ErrBadPadding, got %v", dec.Err)
+	}
+}
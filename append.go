@@ -0,0 +1,106 @@
+package bitflux
+
+import "math"
+
+// The Append functions append the encoding of a value to dst and return the extended
+// slice, producing the same bytes as the matching EncLE/EncBE method without an io.Writer.
+
+// AppendU8 appends a uint8 value to dst.
+func AppendU8(dst []byte, v uint8) []byte { return append(dst, v) }
+
+// AppendI8 appends an int8 value to dst.
+func AppendI8(dst []byte, v int8) []byte { return append(dst, byte(v)) }
+
+// AppendU16LE appends a uint16 value in little-endian format to dst.
+func AppendU16LE(dst []byte, v uint16) []byte {
+	return append(dst,
+		byte(v),
+		byte(v>>8),
+	)
+}
+
+// AppendU16BE appends a uint16 value in big-endian format to dst.
+func AppendU16BE(dst []byte, v uint16) []byte {
+	return append(dst,
+		byte(v>>8),
+		byte(v),
+	)
+}
+
+// AppendU32LE appends a uint32 value in little-endian format to dst.
+func AppendU32LE(dst []byte, v uint32) []byte {
+	return append(dst,
+		byte(v),
+		byte(v>>8),
+		byte(v>>16),
+		byte(v>>24),
+	)
+}
+
+// AppendU32BE appends a uint32 value in big-endian format to dst.
+func AppendU32BE(dst []byte, v uint32) []byte {
+	return append(dst,
+		byte(v>>24),
+		byte(v>>16),
+		byte(v>>8),
+		byte(v),
+	)
+}
+
+// AppendU64LE appends a uint64 value in little-endian format to dst.
+func AppendU64LE(dst []byte, v uint64) []byte {
+	return append(dst,
+		byte(v),
+		byte(v>>8),
+		byte(v>>16),
+		byte(v>>24),
+		byte(v>>32),
+		byte(v>>40),
+		byte(v>>48),
+		byte(v>>56),
+	)
+}
+
+// AppendU64BE appends a uint64 value in big-endian format to dst.
+func AppendU64BE(dst []byte, v uint64) []byte {
+	return append(dst,
+		byte(v>>56),
+		byte(v>>48),
+		byte(v>>40),
+		byte(v>>32),
+		byte(v>>24),
+		byte(v>>16),
+		byte(v>>8),
+		byte(v),
+	)
+}
+
+// AppendI16LE appends an int16 value in little-endian format to dst.
+func AppendI16LE(dst []byte, v int16) []byte { return AppendU16LE(dst, uint16(v)) }
+
+// AppendI16BE appends an int16 value in big-endian format to dst.
+func AppendI16BE(dst []byte, v int16) []byte { return AppendU16BE(dst, uint16(v)) }
+
+// AppendI32LE appends an int32 value in little-endian format to dst.
+func AppendI32LE(dst []byte, v int32) []byte { return AppendU32LE(dst, uint32(v)) }
+
+// AppendI32BE appends an int32 value in big-endian format to dst.
+func AppendI32BE(dst []byte, v int32) []byte { return AppendU32BE(dst, uint32(v)) }
+
+// AppendI64LE appends an int64 value in little-endian format to dst.
+func AppendI64LE(dst []byte, v int64) []byte { return AppendU64LE(dst, uint64(v)) }
+
+// AppendI64BE appends an int64 value in big-endian format to dst.
+func AppendI64BE(dst []byte, v int64) []byte { return AppendU64BE(dst, uint64(v)) }
+
+// AppendF32LE appends a float32 value in little-endian format to dst using IEEE 754 representation.
+func AppendF32LE(dst []byte, v float32) []byte { return AppendU32LE(dst, math.Float32bits(v)) }
+
+// AppendF32BE appends a float32 value in big-endian format to dst using IEEE 754 representation.
+func AppendF32BE(dst []byte, v float32) []byte { return AppendU32BE(dst, math.Float32bits(v)) }
+
+// AppendF64LE appends a float64 value in little-endian format to dst using IEEE 754 representation.
+func AppendF64LE(dst []byte, v float64) []byte { return AppendU64LE(dst, math.Float64bits(v)) }
+
+// AppendF64BE appends a float64 value in big-endian format to dst using IEEE 754 representation.
+func AppendF64BE(dst []byte, v float64) []byte { return AppendU64BE(dst, math.Float64bits(v)) }
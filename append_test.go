@@ -0,0 +1,86 @@
+package bitflux
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestAppendMatchesEncoders(t *testing.T) {
+	var le, be bytes.Buffer
+	encLE, encBE := NewEncLE(&le), NewEncBE(&be)
+	var gotLE, gotBE []byte
+
+	for _, v := range []uint64{0, 1, 0x0102030405060708, math.MaxUint64} {
+		encLE.U8(uint8(v))
+		encBE.U8(uint8(v))
+		gotLE = AppendU8(gotLE, uint8(v))
+		gotBE = AppendU8(gotBE, uint8(v))
+
+		encLE.I8(int8(v))
+		encBE.I8(int8(v))
+		gotLE = AppendI8(gotLE, int8(v))
+		gotBE = AppendI8(gotBE, int8(v))
+
+		encLE.U16(uint16(v))
+		encBE.U16(uint16(v))
+		gotLE = AppendU16LE(gotLE, uint16(v))
+		gotBE = AppendU16BE(gotBE, uint16(v))
+
+		encLE.U32(uint32(v))
+		encBE.U32(uint32(v))
+		gotLE = AppendU32LE(gotLE, uint32(v))
+		gotBE = AppendU32BE(gotBE, uint32(v))
+
+		encLE.U64(v)
+		encBE.U64(v)
+		gotLE = AppendU64LE(gotLE, v)
+		gotBE = AppendU64BE(gotBE, v)
+
+		encLE.I16(int16(v))
+		encBE.I16(int16(v))
+		gotLE = AppendI16LE(gotLE, int16(v))
+		gotBE = AppendI16BE(gotBE, int16(v))
+
+		encLE.I32(int32(v))
+		encBE.I32(int32(v))
+		gotLE = AppendI32LE(gotLE, int32(v))
+		gotBE = AppendI32BE(gotBE, int32(v))
+
+		encLE.I64(int64(v))
+		encBE.I64(int64(v))
+		gotLE = AppendI64LE(gotLE, int64(v))
+		gotBE = AppendI64BE(gotBE, int64(v))
+
+		f := math.Float64frombits(v)
+		encLE.F32(float32(f))
+		encBE.F32(float32(f))
+		gotLE = AppendF32LE(gotLE, float32(f))
+		gotBE = AppendF32BE(gotBE, float32(f))
+
+		encLE.F64(f)
+		encBE.F64(f)
+		gotLE = AppendF64LE(gotLE, f)
+		gotBE = AppendF64BE(gotBE, f)
+	}
+
+	if !bytes.Equal(gotLE, le.Bytes()) {
+		t.Errorf("little-endian: got=% x, want=% x", gotLE, le.Bytes())
+	}
+	if !bytes.Equal(gotBE, be.Bytes()) {
+		t.Errorf("big-endian: got=% x, want=% x", gotBE, be.Bytes())
+	}
+}
+
+func TestAppendNoAllocs(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	allocs := testing.AllocsPerRun(100, func() {
+		b := AppendU16BE(buf[:0], 0x0102)
+		b = AppendU32LE(b, 0x03040506)
+		b = AppendF64BE(b, 1.5)
+		_ = b
+	})
+	if allocs != 0 {
+		t.Fatalf("got %v allocs, want 0", allocs)
+	}
+}
@@ -0,0 +1,261 @@
+package bitflux
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrBitOverflow is reported when a value does not fit in the width of its bit field.
+	ErrBitOverflow = errors.New("bitflux: value overflows bit field")
+	// ErrBitLayout is reported for bit field layouts with overlapping or out of range fields.
+	ErrBitLayout = errors.New("bitflux: invalid bit field layout")
+	// ErrBitField is reported when a named bit field does not exist in the layout.
+	ErrBitField = errors.New("bitflux: unknown bit field")
+)
+
+// BitField describes a named field packed into a word.
+type BitField struct {
+	Name   string // Field name, used as the map key or struct field/tag name
+	Offset int    // Bit offset of the field's least significant bit
+	Width  int    // Width of the field in bits
+}
+
+func (f BitField) mask() uint64 {
+	if f.Width == 64 {
+		return ^uint64(0)
+	}
+	return 1<<f.Width - 1
+}
+
+// inRange reports whether the field is not empty and lies within a word of size bits.
+func (f BitField) inRange(size int) bool {
+	return f.Width >= 1 && f.Width <= size && f.Offset >= 0 && f.Offset <= size-f.Width
+}
+
+// BitLayout is a set of named bit fields within a word of up to 64 bits, such as
+// a status word read with DecBE.U16 or written with EncBE.U32.
+//
+// Layouts built as literals or whose Fields change after NewBitLayout are looked up
+// by scanning Fields. Their fields are checked as they are used: fields outside the
+// word are reported as ErrBitLayout and left out by Unpack; overlaps are not detected.
+type BitLayout struct {
+	Size   int        // Word size in bits
+	Fields []BitField // Fields in declaration order
+	index  map[string]int
+}
+
+// lookup returns the position of the named field in Fields. The index built by
+// NewBitLayout is used only while it still matches Fields.
+func (l *BitLayout) lookup(name string) (int, bool) {
+	if i, ok := l.index[name]; ok && len(l.index) == len(l.Fields) && l.Fields[i].Name == name {
+		return i, true
+	}
+	for i, f := range l.Fields {
+		if f.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// NewBitLayout creates a layout for a word of size bits.
+// It returns ErrBitLayout if a field is empty, outside the word or overlaps another field.
+func NewBitLayout(size int, fields ...BitField) (*BitLayout, error) {
+	if size < 1 || size > 64 {
+		return nil, fmt.Errorf("%w: word size %d", ErrBitLayout, size)
+	}
+	l := &BitLayout{Size: size, Fields: fields, index: make(map[string]int, len(fields))}
+	var used uint64
+	for i, f := range fields {
+		if !f.inRange(size) {
+			return nil, fmt.Errorf("%w: field %q out of range", ErrBitLayout, f.Name)
+		}
+		if _, ok := l.index[f.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrBitLayout, f.Name)
+		}
+		m := f.mask() << f.Offset
+		if used&m != 0 {
+			return nil, fmt.Errorf("%w: field %q overlaps another field", ErrBitLayout, f.Name)
+		}
+		used |= m
+		l.index[f.Name] = i
+	}
+	return l, nil
+}
+
+// wordSize returns the word size in bits, or 64 if Size is out of range.
+func (l *BitLayout) wordSize() int {
+	if l.Size < 1 || l.Size > 64 {
+		return 64
+	}
+	return l.Size
+}
+
+// checkField returns an error wrapping ErrBitLayout if f does not lie within the word.
+func (l *BitLayout) checkField(f BitField) error {
+	if !f.inRange(l.wordSize()) {
+		return fmt.Errorf("%w: field %q out of range", ErrBitLayout, f.Name)
+	}
+	return nil
+}
+
+// field returns the named field.
+func (l *BitLayout) field(name string) (BitField, error) {
+	i, ok := l.lookup(name)
+	if !ok {
+		return BitField{}, fmt.Errorf("%w: %q", ErrBitField, name)
+	}
+	return l.Fields[i], l.checkField(l.Fields[i])
+}
+
+// Get extracts the named field from word.
+func (l *BitLayout) Get(word uint64, name string) (uint64, error) {
+	f, err := l.field(name)
+	if err != nil {
+		return 0, err
+	}
+	return word >> f.Offset & f.mask(), nil
+}
+
+// Set returns word with the named field replaced by v.
+// It returns ErrBitOverflow if v does not fit in the field.
+func (l *BitLayout) Set(word uint64, name string, v uint64) (uint64, error) {
+	f, err := l.field(name)
+	if err != nil {
+		return word, err
+	}
+	if v&^f.mask() != 0 {
+		return word, fmt.Errorf("%w: %q = %d", ErrBitOverflow, name, v)
+	}
+	return word&^(f.mask()<<f.Offset) | v<<f.Offset, nil
+}
+
+// Pack packs values into a word. Fields missing from values are zero.
+// It returns ErrBitField for names not in the layout and ErrBitOverflow for values that do not fit.
+func (l *BitLayout) Pack(values map[string]uint64) (uint64, error) {
+	var word uint64
+	for name, v := range values {
+		var err error
+		if word, err = l.Set(word, name, v); err != nil {
+			return 0, err
+		}
+	}
+	return word, nil
+}
+
+// Unpack extracts every field of word into a map keyed by field name.
+// Fields outside the word are left out.
+func (l *BitLayout) Unpack(word uint64) map[string]uint64 {
+	values := make(map[string]uint64, len(l.Fields))
+	for _, f := range l.Fields {
+		if l.checkField(f) == nil {
+			values[f.Name] = word >> f.Offset & f.mask()
+		}
+	}
+	return values
+}
+
+// PackStruct packs the fields of the struct (or pointer to struct) v into a word.
+// Struct fields are matched to bit fields by their `bits` tag, or by name if untagged;
+// fields tagged `bits:"-"` and fields with no matching bit field are ignored.
+// Supported field kinds are bool, signed and unsigned integers. Signed values are stored
+// in two's complement and must fit in the field's width.
+func (l *BitLayout) PackStruct(v any) (uint64, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("bitflux: PackStruct of non-struct %T", v)
+	}
+	var word uint64
+	for i := 0; i < rv.NumField(); i++ {
+		f, ok := l.structField(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if err := l.checkField(f); err != nil {
+			return 0, err
+		}
+		var u uint64
+		fv := rv.Field(i)
+		switch fv.Kind() {
+		case reflect.Bool:
+			if fv.Bool() {
+				u = 1
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			u = fv.Uint()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s := fv.Int()
+			if f.Width < 64 && (s < -1<<(f.Width-1) || s >= 1<<(f.Width-1)) {
+				return 0, fmt.Errorf("%w: %q = %d", ErrBitOverflow, f.Name, s)
+			}
+			u = uint64(s) & f.mask()
+		default:
+			return 0, fmt.Errorf("bitflux: unsupported bit field kind %s for %q", fv.Kind(), f.Name)
+		}
+		var err error
+		if word, err = l.Set(word, f.Name, u); err != nil {
+			return 0, err
+		}
+	}
+	return word, nil
+}
+
+// UnpackStruct extracts the fields of word into the struct pointed to by v.
+// Fields are matched as described for PackStruct; signed fields are sign extended.
+func (l *BitLayout) UnpackStruct(word uint64, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bitflux: UnpackStruct of non-struct pointer %T", v)
+	}
+	rv = rv.Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		f, ok := l.structField(rv.Type().Field(i))
+		if !ok {
+			continue
+		}
+		if err := l.checkField(f); err != nil {
+			return err
+		}
+		u := word >> f.Offset & f.mask()
+		fv := rv.Field(i)
+		switch fv.Kind() {
+		case reflect.Bool:
+			fv.SetBool(u != 0)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if fv.OverflowUint(u) {
+				return fmt.Errorf("%w: %q = %d", ErrBitOverflow, f.Name, u)
+			}
+			fv.SetUint(u)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s := int64(u<<(64-f.Width)) >> (64 - f.Width)
+			if fv.OverflowInt(s) {
+				return fmt.Errorf("%w: %q = %d", ErrBitOverflow, f.Name, s)
+			}
+			fv.SetInt(s)
+		default:
+			return fmt.Errorf("bitflux: unsupported bit field kind %s for %q", fv.Kind(), f.Name)
+		}
+	}
+	return nil
+}
+
+// structField returns the bit field matching a struct field, if any.
+func (l *BitLayout) structField(sf reflect.StructField) (BitField, bool) {
+	if !sf.IsExported() {
+		return BitField{}, false
+	}
+	name := sf.Name
+	if tag, ok := sf.Tag.Lookup("bits"); ok {
+		if tag == "-" {
+			return BitField{}, false
+		}
+		name = tag
+	}
+	i, ok := l.lookup(name)
+	if !ok {
+		return BitField{}, false
+	}
+	return l.Fields[i], true
+}
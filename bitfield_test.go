@@ -0,0 +1,151 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testStatusLayout(t *testing.T) *BitLayout {
+	t.Helper()
+	l, err := NewBitLayout(16,
+		BitField{Name: "Running", Offset: 0, Width: 1},
+		BitField{Name: "Fault", Offset: 1, Width: 1},
+		BitField{Name: "mode", Offset: 4, Width: 3},
+		BitField{Name: "Offset", Offset: 8, Width: 8},
+	)
+	if err != nil {
+		t.Fatalf("NewBitLayout error: %v", err)
+	}
+	return l
+}
+
+func TestBitLayoutPackMap(t *testing.T) {
+	l := testStatusLayout(t)
+	word, err := l.Pack(map[string]uint64{"Running": 1, "mode": 5, "Offset": 0xAB})
+	if err != nil {
+		t.Fatalf("Pack error: %v", err)
+	}
+	if word != 0xAB51 {
+		t.Fatalf("got=%#04x, want=0xab51", word)
+	}
+
+	want := map[string]uint64{"Running": 1, "Fault": 0, "mode": 5, "Offset": 0xAB}
+	if got := l.Unpack(word); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Unpack: got=%v, want=%v", got, want)
+	}
+
+	if _, err := l.Pack(map[string]uint64{"mode": 8}); !errors.Is(err, ErrBitOverflow) {
+		t.Errorf("expected ErrBitOverflow, got %v", err)
+	}
+	if _, err := l.Pack(map[string]uint64{"Missing": 1}); !errors.Is(err, ErrBitField) {
+		t.Errorf("expected ErrBitField, got %v", err)
+	}
+}
+
+func TestBitLayoutLiteral(t *testing.T) {
+	l := &BitLayout{Size: 8, Fields: []BitField{{Name: "Low", Offset: 0, Width: 4}, {Name: "High", Offset: 4, Width: 4}}}
+	word, err := l.Set(0, "High", 0xA)
+	if err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if word != 0xA0 {
+		t.Fatalf("got=%#02x, want=0xa0", word)
+	}
+
+	// Fields replaced after NewBitLayout must not be looked up through the old index.
+	l = testStatusLayout(t)
+	l.Fields = []BitField{{Name: "Fault", Offset: 0, Width: 2}, {Name: "Running", Offset: 2, Width: 1}}
+	if got, err := l.Get(0x06, "Running"); err != nil || got != 1 {
+		t.Errorf("Get Running: got=%d, %v, want=1", got, err)
+	}
+	if got, err := l.Get(0x06, "Fault"); err != nil || got != 2 {
+		t.Errorf("Get Fault: got=%d, %v, want=2", got, err)
+	}
+	if _, err := l.Get(0, "Offset"); !errors.Is(err, ErrBitField) {
+		t.Errorf("expected ErrBitField, got %v", err)
+	}
+
+	// Fields outside the word are reported instead of shifting by a negative amount.
+	l = &BitLayout{Size: 16, Fields: []BitField{
+		{Name: "Neg", Offset: -1, Width: 4},
+		{Name: "Wide", Offset: 60, Width: 8},
+		{Name: "Past", Offset: 14, Width: 4},
+		{Name: "Ok", Offset: 0, Width: 4},
+	}}
+	for _, name := range []string{"Neg", "Wide", "Past"} {
+		if _, err := l.Get(0xFFFF, name); !errors.Is(err, ErrBitLayout) {
+			t.Errorf("Get %s: expected ErrBitLayout, got %v", name, err)
+		}
+		if _, err := l.Set(0, name, 1); !errors.Is(err, ErrBitLayout) {
+			t.Errorf("Set %s: expected ErrBitLayout, got %v", name, err)
+		}
+	}
+	if got := l.Unpack(0xFFFF); !reflect.DeepEqual(got, map[string]uint64{"Ok": 0xF}) {
+		t.Errorf("Unpack: got=%v", got)
+	}
+	var v struct{ Neg, Ok uint8 }
+	if err := l.UnpackStruct(0xFFFF, &v); !errors.Is(err, ErrBitLayout) {
+		t.Errorf("UnpackStruct: expected ErrBitLayout, got %v", err)
+	}
+	if _, err := l.PackStruct(v); !errors.Is(err, ErrBitLayout) {
+		t.Errorf("PackStruct: expected ErrBitLayout, got %v", err)
+	}
+}
+
+func TestBitLayoutStruct(t *testing.T) {
+	type status struct {
+		Running bool
+		Fault   bool
+		Mode    uint8 `bits:"mode"`
+		Offset  int8
+		Note    string `bits:"-"`
+	}
+	l := testStatusLayout(t)
+
+	in := status{Running: true, Mode: 3, Offset: -2}
+	word, err := l.PackStruct(&in)
+	if err != nil {
+		t.Fatalf("PackStruct error: %v", err)
+	}
+	if word != 0xFE31 {
+		t.Fatalf("got=%#04x, want=0xfe31", word)
+	}
+
+	// Round trip through the big-endian encoder and decoder.
+	var buf bytes.Buffer
+	NewEncBE(&buf).U16(uint16(word))
+	var out status
+	if err := l.UnpackStruct(uint64(NewDecBE(&buf).U16()), &out); err != nil {
+		t.Fatalf("UnpackStruct error: %v", err)
+	}
+	if out != in {
+		t.Fatalf("got=%+v, want=%+v", out, in)
+	}
+
+	if _, err := l.PackStruct(status{Offset: 0, Mode: 9}); !errors.Is(err, ErrBitOverflow) {
+		t.Errorf("expected ErrBitOverflow, got %v", err)
+	}
+}
+
+func TestBitLayoutInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		fields []BitField
+	}{
+		{"overlap", 8, []BitField{{"a", 0, 4}, {"b", 3, 2}}},
+		{"out of range", 8, []BitField{{"a", 6, 3}}},
+		{"zero width", 8, []BitField{{"a", 0, 0}}},
+		{"duplicate", 8, []BitField{{"a", 0, 1}, {"a", 1, 1}}},
+		{"word size", 65, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBitLayout(tt.size, tt.fields...); !errors.Is(err, ErrBitLayout) {
+				t.Fatalf("expected ErrBitLayout, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package bitflux
+
+import (
+	"fmt"
+	"io"
+)
+
+// BitReader reads bit fields from Buf, read as one little-endian or big-endian integer.
+// In little-endian order bit 0 is the least significant bit of Buf[0] and fields are
+// read least significant bit first; in big-endian order bit 0 is the most significant
+// bit of Buf[0] and fields are read most significant bit first. It can be used as a
+// value without heap allocation:
+//
+//	r := bitflux.BitReader{Buf: payload, Pos: 12}
+//	v := r.Bits(10)
+type BitReader struct {
+	Buf       []byte // Bits to read
+	BigEndian bool   // Bit order, see BitReader
+	Pos       int    // Position of the next bit to read
+	Err       error  // First error encountered while reading
+}
+
+// NewBitReader creates a bit reader over buf in the given byte order.
+func NewBitReader(buf []byte, bigEndian bool) *BitReader {
+	return &BitReader{Buf: buf, BigEndian: bigEndian}
+}
+
+// checkBits returns an error if n bits at pos are not a valid field of a buffer of size bytes.
+func checkBits(pos, n, size int, short error) error {
+	if n < 1 || n > 64 {
+		return fmt.Errorf("bitflux: invalid bit field width %d", n)
+	}
+	if pos < 0 || pos+n > 8*size {
+		return short
+	}
+	return nil
+}
+
+// Bits reads an unsigned field of n bits, 1 to 64, and advances Pos.
+// Reading past the end of Buf sets Err to io.ErrUnexpectedEOF.
+func (r *BitReader) Bits(n int) uint64 {
+	if r.Err != nil {
+		return 0
+	}
+	if r.Err = checkBits(r.Pos, n, len(r.Buf), io.ErrUnexpectedEOF); r.Err != nil {
+		return 0
+	}
+	var v uint64
+	for i := 0; i < n; {
+		pos := r.Pos + i
+		off := pos % 8
+		k := min(8-off, n-i)
+		if r.BigEndian {
+			v = v<<k | uint64(r.Buf[pos/8]>>(8-off-k))&(1<<k-1)
+		} else {
+			v |= uint64(r.Buf[pos/8]>>off) & (1<<k - 1) << i
+		}
+		i += k
+	}
+	r.Pos += n
+	return v
+}
+
+// Signed reads a two's complement field of n bits and sign-extends it.
+func (r *BitReader) Signed(n int) int64 {
+	v := r.Bits(n)
+	if n < 64 && v>>(n-1)&1 != 0 {
+		v |= ^uint64(0) << n
+	}
+	return int64(v)
+}
+
+// Bool reads a single bit.
+func (r *BitReader) Bool() bool { return r.Bits(1) != 0 }
+
+// BitWriter writes bit fields into Buf in place, with the bit numbering of BitReader.
+// Bits outside the written fields are left unchanged and Buf never grows.
+type BitWriter struct {
+	Buf       []byte // Bits to write
+	BigEndian bool   // Bit order, see BitReader
+	Pos       int    // Position of the next bit to write
+	Err       error  // First error encountered while writing
+}
+
+// NewBitWriter creates a bit writer into buf in the given byte order.
+func NewBitWriter(buf []byte, bigEndian bool) *BitWriter {
+	return &BitWriter{Buf: buf, BigEndian: bigEndian}
+}
+
+// Bits writes v as a field of n bits, 1 to 64, and advances Pos.
+// Writing past the end of Buf sets Err to ErrBufferFull and values that do not fit
+// in n bits set it to an error wrapping ErrBitOverflow; nothing is written then.
+func (w *BitWriter) Bits(n int, v uint64) {
+	if w.Err != nil {
+		return
+	}
+	if w.Err = checkBits(w.Pos, n, len(w.Buf), ErrBufferFull); w.Err != nil {
+		return
+	}
+	if n < 64 && v>>n != 0 {
+		w.Err = fmt.Errorf("%w: %d in %d bits", ErrBitOverflow, v, n)
+		return
+	}
+	for i := 0; i < n; {
+		pos := w.Pos + i
+		off := pos % 8
+		k := min(8-off, n-i)
+		var m, b byte
+		if w.BigEndian {
+			shift := 8 - off - k
+			m = byte(1<<k-1) << shift
+			b = byte(v>>(n-i-k)) << shift
+		} else {
+			m = byte(1<<k-1) << off
+			b = byte(v>>i) << off
+		}
+		w.Buf[pos/8] = w.Buf[pos/8]&^m | b&m
+		i += k
+	}
+	w.Pos += n
+}
+
+// Signed writes v as a two's complement field of n bits.
+// Values outside the range of n bits set Err to an error wrapping ErrBitOverflow.
+func (w *BitWriter) Signed(n int, v int64) {
+	if w.Err == nil && n >= 1 && n < 64 && (v < -1<<(n-1) || v >= 1<<(n-1)) {
+		w.Err = fmt.Errorf("%w: %d in %d bits", ErrBitOverflow, v, n)
+		return
+	}
+	u := uint64(v)
+	if n >= 1 && n < 64 {
+		u &= 1<<n - 1
+	}
+	w.Bits(n, u)
+}
+
+// Bool writes a single bit.
+func (w *BitWriter) Bool(v bool) {
+	var b uint64
+	if v {
+		b = 1
+	}
+	w.Bits(1, b)
+}
@@ -0,0 +1,83 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBitReaderWriter(t *testing.T) {
+	p := []byte{0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC, 0xDE, 0xF0, 0x11}
+	for _, tt := range []struct {
+		pos, n int
+		be     bool
+		want   uint64
+	}{
+		{0, 8, false, 0x12},
+		{4, 8, false, 0x41},
+		{12, 12, false, 0x563},
+		{0, 64, false, 0xF0DEBC9A78563412},
+		{4, 64, false, 0x1F0DEBC9A7856341},
+		{0, 8, true, 0x12},
+		{4, 8, true, 0x23},
+		{4, 12, true, 0x234},
+		{0, 64, true, 0x123456789ABCDEF0},
+		{4, 64, true, 0x23456789ABCDEF01},
+		{71, 1, true, 1},
+	} {
+		r := BitReader{Buf: p, BigEndian: tt.be, Pos: tt.pos}
+		if got := r.Bits(tt.n); r.Err != nil || got != tt.want || r.Pos != tt.pos+tt.n {
+			t.Errorf("Bits(%d) at %d, be=%v: got=%#x pos=%d err=%v, want=%#x", tt.n, tt.pos, tt.be, got, r.Pos, r.Err, tt.want)
+		}
+		q := bytes.Clone(p)
+		w := BitWriter{Buf: q, BigEndian: tt.be, Pos: tt.pos}
+		w.Bits(tt.n, 0)
+		if r := (BitReader{Buf: q, BigEndian: tt.be, Pos: tt.pos}); r.Bits(tt.n) != 0 {
+			t.Errorf("Bits(%d, 0) at %d, be=%v: field not cleared", tt.n, tt.pos, tt.be)
+		}
+		w.Pos = tt.pos
+		w.Bits(tt.n, tt.want)
+		if w.Err != nil || !bytes.Equal(q, p) {
+			t.Errorf("Bits(%d) at %d, be=%v: got=% x err=%v, want=% x", tt.n, tt.pos, tt.be, q, w.Err, p)
+		}
+	}
+}
+
+func TestBitReaderSequence(t *testing.T) {
+	var buf [2]byte
+	w := NewBitWriter(buf[:], true)
+	w.Bool(true)
+	w.Signed(4, -3)
+	w.Bits(11, 0x5A5)
+	if w.Err != nil || !bytes.Equal(buf[:], []byte{0xED, 0xA5}) {
+		t.Fatalf("BitWriter: got=% x err=%v", buf, w.Err)
+	}
+	r := NewBitReader(buf[:], true)
+	if b, s, v := r.Bool(), r.Signed(4), r.Bits(11); r.Err != nil || !b || s != -3 || v != 0x5A5 {
+		t.Fatalf("BitReader: got=%v %d %#x err=%v", b, s, v, r.Err)
+	}
+	if r.Bool(); r.Err != io.ErrUnexpectedEOF {
+		t.Fatalf("read past end: got err=%v", r.Err)
+	}
+}
+
+func TestBitWriterErrors(t *testing.T) {
+	var buf [2]byte
+	w := NewBitWriter(buf[:], false)
+	if w.Bits(4, 16); !errors.Is(w.Err, ErrBitOverflow) || buf != [2]byte{} {
+		t.Fatalf("overflow: got=% x err=%v", buf, w.Err)
+	}
+	w = NewBitWriter(buf[:], false)
+	if w.Signed(4, -9); !errors.Is(w.Err, ErrBitOverflow) {
+		t.Fatalf("signed overflow: got err=%v", w.Err)
+	}
+	w = &BitWriter{Buf: buf[:], Pos: 10}
+	if w.Bits(7, 0); w.Err != ErrBufferFull || buf != [2]byte{} {
+		t.Fatalf("past end: got=% x err=%v", buf, w.Err)
+	}
+	r := NewBitReader(buf[:], false)
+	if r.Bits(65); r.Err == nil {
+		t.Fatal("65-bit field: expected error")
+	}
+}
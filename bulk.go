@@ -0,0 +1,27 @@
+package bitflux
+
+import "unsafe"
+
+// bulkChunk is the size of the buffer, allocated once per encoder or decoder by bulkBuf,
+// that the slice methods convert data in, so a whole slice is pushed or pulled in a few
+// large writes or reads rather than one per element.
+const bulkChunk = 1024
+
+// hostLE and hostBE report the byte order the host stores integers in. When the
+// requested byte order matches, the slice methods copy the slice memory directly
+// instead of converting element by element.
+var (
+	hostLE = func() bool {
+		x := uint16(1)
+		return *(*byte)(unsafe.Pointer(&x)) == 1
+	}()
+	hostBE = !hostLE
+)
+
+// sliceBytes returns the memory backing v as a byte slice, without copying.
+func sliceBytes[T uint16 | uint32 | uint64 | int16 | int32 | int64 | float32 | float64](v []T) []byte {
+	if len(v) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(v))), len(v)*int(unsafe.Sizeof(v[0])))
+}
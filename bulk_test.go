@@ -0,0 +1,275 @@
+package bitflux
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// bulkTestData returns n values of every bulk type with mixed bit patterns.
+func bulkTestData(n int) (u16 []uint16, u32 []uint32, u64 []uint64, i16 []int16, i32 []int32, i64 []int64, f32 []float32, f64 []float64) {
+	for i := 0; i < n; i++ {
+		x := uint64(i)*0x9E3779B97F4A7C15 + 1
+		u16 = append(u16, uint16(x))
+		u32 = append(u32, uint32(x))
+		u64 = append(u64, x)
+		i16 = append(i16, int16(x>>8))
+		i32 = append(i32, int32(x>>16))
+		i64 = append(i64, int64(x))
+		f32 = append(f32, float32(i)*-1.5)
+		f64 = append(f64, math.Float64frombits(x>>2))
+	}
+	return
+}
+
+func TestBulkLE(t *testing.T) {
+	u16, u32, u64, i16, i32, i64, f32, f64 := bulkTestData(700)
+
+	// Reference encoding, one value at a time.
+	var want bytes.Buffer
+	ref := NewEncLE(&want)
+	for i := range u16 {
+		ref.U16(u16[i])
+	}
+	for i := range u32 {
+		ref.U32(u32[i])
+	}
+	for i := range u64 {
+		ref.U64(u64[i])
+	}
+	for i := range i16 {
+		ref.I16(i16[i])
+	}
+	for i := range i32 {
+		ref.I32(i32[i])
+	}
+	for i := range i64 {
+		ref.I64(i64[i])
+	}
+	for i := range f32 {
+		ref.F32(f32[i])
+	}
+	for i := range f64 {
+		ref.F64(f64[i])
+	}
+
+	var got bytes.Buffer
+	enc := NewEncLE(&got)
+	enc.U16s(u16)
+	enc.U32s(u32)
+	enc.U64s(u64)
+	enc.I16s(i16)
+	enc.I32s(i32)
+	enc.I64s(i64)
+	enc.F32s(f32)
+	enc.F64s(f64)
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatalf("slice encoding differs from per-value encoding")
+	}
+	if enc.N != int64(want.Len()) {
+		t.Errorf("N: got=%d, want=%d", enc.N, want.Len())
+	}
+
+	dec := NewDecLE(&got)
+	outU16, outU32, outU64 := make([]uint16, len(u16)), make([]uint32, len(u32)), make([]uint64, len(u64))
+	outI16, outI32, outI64 := make([]int16, len(i16)), make([]int32, len(i32)), make([]int64, len(i64))
+	outF32, outF64 := make([]float32, len(f32)), make([]float64, len(f64))
+	dec.U16s(outU16)
+	dec.U32s(outU32)
+	dec.U64s(outU64)
+	dec.I16s(outI16)
+	dec.I32s(outI32)
+	dec.I64s(outI64)
+	dec.F32s(outF32)
+	dec.F64s(outF64)
+	if dec.Err != nil {
+		t.Fatalf("unexpected decode error: %v", dec.Err)
+	}
+	for _, c := range []struct{ got, want any }{
+		{outU16, u16}, {outU32, u32}, {outU64, u64},
+		{outI16, i16}, {outI32, i32}, {outI64, i64},
+		{outF32, f32}, {outF64, f64},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%T round trip mismatch", c.want)
+		}
+	}
+}
+
+func TestBulkBE(t *testing.T) {
+	u16, u32, u64, i16, i32, i64, f32, f64 := bulkTestData(700)
+
+	var want bytes.Buffer
+	ref := NewEncBE(&want)
+	for i := range u16 {
+		ref.U16(u16[i])
+	}
+	for i := range u32 {
+		ref.U32(u32[i])
+	}
+	for i := range u64 {
+		ref.U64(u64[i])
+	}
+	for i := range i16 {
+		ref.I16(i16[i])
+	}
+	for i := range i32 {
+		ref.I32(i32[i])
+	}
+	for i := range i64 {
+		ref.I64(i64[i])
+	}
+	for i := range f32 {
+		ref.F32(f32[i])
+	}
+	for i := range f64 {
+		ref.F64(f64[i])
+	}
+
+	var got bytes.Buffer
+	enc := NewEncBE(&got)
+	enc.U16s(u16)
+	enc.U32s(u32)
+	enc.U64s(u64)
+	enc.I16s(i16)
+	enc.I32s(i32)
+	enc.I64s(i64)
+	enc.F32s(f32)
+	enc.F64s(f64)
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Fatalf("slice encoding differs from per-value encoding")
+	}
+
+	dec := NewDecBE(&got)
+	outU16, outU32, outU64 := make([]uint16, len(u16)), make([]uint32, len(u32)), make([]uint64, len(u64))
+	outI16, outI32, outI64 := make([]int16, len(i16)), make([]int32, len(i32)), make([]int64, len(i64))
+	outF32, outF64 := make([]float32, len(f32)), make([]float64, len(f64))
+	dec.U16s(outU16)
+	dec.U32s(outU32)
+	dec.U64s(outU64)
+	dec.I16s(outI16)
+	dec.I32s(outI32)
+	dec.I64s(outI64)
+	dec.F32s(outF32)
+	dec.F64s(outF64)
+	if dec.Err != nil {
+		t.Fatalf("unexpected decode error: %v", dec.Err)
+	}
+	for _, c := range []struct{ got, want any }{
+		{outU16, u16}, {outU32, u32}, {outU64, u64},
+		{outI16, i16}, {outI32, i32}, {outI64, i64},
+		{outF32, f32}, {outF64, f64},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%T round trip mismatch", c.want)
+		}
+	}
+}
+
+func TestBulkDecodeShort(t *testing.T) {
+	dec := NewDecBE(bytes.NewReader([]byte{0x00, 0x01, 0x00}))
+	v := make([]uint16, 2)
+	dec.U16s(v)
+	if dec.Err == nil {
+		t.Fatal("expected error for short input")
+	}
+}
+
+func TestBulkGenericPath(t *testing.T) {
+	le, be := hostLE, hostBE
+	hostLE, hostBE = false, false
+	defer func() { hostLE, hostBE = le, be }()
+	t.Run("LE", TestBulkLE)
+	t.Run("BE", TestBulkBE)
+}
+
+// Benchmark comparison for a 10,000 sample waveform: one call per value, the
+// generic slice path and the native byte-order fast path.
+func BenchmarkEncF32s(b *testing.B) {
+	samples := make([]float32, 10_000)
+	for i := range samples {
+		samples[i] = float32(math.Sin(float64(i) / 100))
+	}
+	var buf bytes.Buffer
+	buf.Grow(4 * len(samples))
+
+	b.Run("PerValue", func(b *testing.B) {
+		b.SetBytes(int64(4 * len(samples)))
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			enc := NewEncLE(&buf)
+			for _, v := range samples {
+				enc.F32(v)
+			}
+		}
+	})
+	b.Run("Generic", func(b *testing.B) {
+		le, be := hostLE, hostBE
+		hostLE, hostBE = false, false
+		defer func() { hostLE, hostBE = le, be }()
+		b.SetBytes(int64(4 * len(samples)))
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			NewEncLE(&buf).F32s(samples)
+		}
+	})
+	b.Run("Native", func(b *testing.B) {
+		b.SetBytes(int64(4 * len(samples)))
+		for i := 0; i < b.N; i++ {
+			buf.Reset()
+			if hostLE {
+				NewEncLE(&buf).F32s(samples)
+			} else {
+				NewEncBE(&buf).F32s(samples)
+			}
+		}
+	})
+}
+
+func BenchmarkDecF32s(b *testing.B) {
+	data := make([]byte, 4*10_000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	samples := make([]float32, len(data)/4)
+	r := bytes.NewReader(data)
+
+	b.Run("PerValue", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			r.Reset(data)
+			dec := NewDecLE(r)
+			for j := range samples {
+				samples[j] = dec.F32()
+			}
+		}
+	})
+	b.Run("Generic", func(b *testing.B) {
+		le, be := hostLE, hostBE
+		hostLE, hostBE = false, false
+		defer func() { hostLE, hostBE = le, be }()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			r.Reset(data)
+			NewDecLE(r).F32s(samples)
+		}
+	})
+	b.Run("Native", func(b *testing.B) {
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			r.Reset(data)
+			if hostLE {
+				NewDecLE(r).F32s(samples)
+			} else {
+				NewDecBE(r).F32s(samples)
+			}
+		}
+	})
+}
@@ -0,0 +1,7 @@
+package can
+
+// motorolaStart converts a DBC Motorola start bit, the position of the signal's
+// most significant bit with bit 0 being the least significant bit of p[0], into
+// the big-endian bit position of bitflux.BitReader, counted from the most
+// significant bit of p[0].
+func motorolaStart(start int) int { return start/8*8 + 7 - start%8 }
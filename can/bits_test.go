@@ -0,0 +1,11 @@
+package can
+
+import "testing"
+
+func TestMotorolaStart(t *testing.T) {
+	for start, want := range map[int]int{7: 0, 0: 7, 15: 8, 11: 12, 63: 56} {
+		if got := motorolaStart(start); got != want {
+			t.Errorf("motorolaStart(%d): got=%d, want=%d", start, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package can decodes and encodes the signals of CAN and CAN FD payloads as
+// described by DBC files.
+//
+// Signals are bit fields of a payload. Intel signals are little-endian: the
+// payload is read as one little-endian integer and the start bit is the
+// signal's least significant bit. Motorola signals are big-endian: the payload
+// is read as one big-endian integer and the start bit is the signal's most
+// significant bit, numbered as in DBC files (bit 7 is the most significant
+// bit of byte 0). Signals are read and written with bitflux.BitReader and
+// bitflux.BitWriter:
+//
+//	db, err := can.Parse(f)
+//	msg, _ := db.Message(0x123)
+//	values, err := msg.Decode(payload)
+//	fmt.Println(values["EngineSpeed"], msg.Signal("EngineSpeed").Unit)
+package can
+
+import (
+	"errors"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// MaxData is the maximum payload size of a CAN FD frame. Classic CAN frames carry up to 8 bytes.
+const MaxData = 64
+
+// validSize reports whether n is a payload size allowed in CAN or CAN FD frames.
+func validSize(n int) bool {
+	switch n {
+	case 0, 1, 2, 3, 4, 5, 6, 7, 8, 12, 16, 20, 24, 32, 48, 64:
+		return true
+	}
+	return false
+}
+
+// ValueType is the type of the raw value of a signal.
+type ValueType uint8
+
+// Signal value types, selected with SIG_VALTYPE_ in DBC files.
+const (
+	Integer ValueType = iota // Signed or unsigned integer of the signal's length
+	Float32                  // IEEE 754 single-precision float, 32 bits
+	Float64                  // IEEE 754 double-precision float, 64 bits
+)
+
+var valueTypes = bitflux.NewEnum("ValueType", 1, map[ValueType]string{
+	Integer: "Integer",
+	Float32: "Float32",
+	Float64: "Float64",
+})
+
+func (t ValueType) String() string { return valueTypes.String(t) }
+
+var (
+	// ErrSyntax is reported for DBC definitions that cannot be parsed.
+	ErrSyntax = errors.New("can: DBC syntax error")
+	// ErrPayload is reported for payloads too short for a signal or larger than MaxData.
+	ErrPayload = errors.New("can: invalid payload size")
+	// ErrRange is reported when a value does not fit in its signal.
+	ErrRange = errors.New("can: value out of signal range")
+	// ErrSignal is reported for signal names that are not defined in the message.
+	ErrSignal = errors.New("can: unknown signal")
+)
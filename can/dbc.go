@@ -0,0 +1,193 @@
+package can
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// extendedFlag marks identifiers of extended frames in DBC files.
+const extendedFlag = 1 << 31
+
+// independentMessage is the pseudo-message holding signals not assigned to any message.
+const independentMessage = "VECTOR__INDEPENDENT_SIG_MSG"
+
+// Database holds the messages defined in a DBC file.
+type Database struct {
+	Messages []*Message // Messages in declaration order
+
+	byID map[uint32]*Message
+}
+
+var (
+	messageLine   = regexp.MustCompile(`^BO_\s+(\d+)\s+(\w+)\s*:\s*(\d+)\s+(\w+)`)
+	signalLine    = regexp.MustCompile(`^SG_\s+(\w+)\s*(M|m\d+M?)?\s*:\s*(\d+)\|(\d+)@([01])([+-])\s*\(([^,]+),([^)]+)\)\s*\[([^|]+)\|([^\]]+)\]\s*"([^"]*)"\s*(.*)$`)
+	valueTypeLine = regexp.MustCompile(`^SIG_VALTYPE_\s+(\d+)\s+(\w+)\s*:\s*([012])\s*;`)
+)
+
+// Parse reads the message (BO_), signal (SG_) and signal value type (SIG_VALTYPE_)
+// definitions of a DBC file from r. Other definitions, such as comments, attributes
+// and value tables, are ignored, as is the VECTOR__INDEPENDENT_SIG_MSG pseudo-message
+// holding unassigned signals. Extended multiplexing is not supported: signals
+// marked both multiplexed and multiplexer are treated as multiplexed only.
+func Parse(r io.Reader) (*Database, error) {
+	db := &Database{byID: make(map[uint32]*Message)}
+	var msg *Message
+	inMessage := false
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		var err error
+		switch {
+		case strings.HasPrefix(text, "BO_ "):
+			msg, err = db.parseMessage(text)
+			inMessage = true
+		case strings.HasPrefix(text, "SG_ "):
+			switch {
+			case !inMessage:
+				err = errors.New("signal outside of a message")
+			case msg != nil:
+				err = msg.parseSignal(text)
+			}
+		case strings.HasPrefix(text, "SIG_VALTYPE_ "):
+			err = db.parseValueType(text)
+		case text == "":
+		default:
+			msg, inMessage = nil, false
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrSyntax, line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Message returns the message with the given identifier, which has bit 31 set for
+// extended frames as in DBC files.
+func (db *Database) Message(id uint32) (*Message, bool) {
+	m, ok := db.byID[id]
+	return m, ok
+}
+
+// MessageByName returns the named message.
+func (db *Database) MessageByName(name string) (*Message, bool) {
+	for _, m := range db.Messages {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+func (db *Database) parseMessage(text string) (*Message, error) {
+	f := messageLine.FindStringSubmatch(text)
+	if f == nil {
+		return nil, fmt.Errorf("malformed message %q", text)
+	}
+	if f[2] == independentMessage {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(f[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("message identifier %s", f[1])
+	}
+	size, _ := strconv.Atoi(f[3])
+	if !validSize(size) {
+		return nil, fmt.Errorf("message %s has invalid size %s", f[2], f[3])
+	}
+	if _, dup := db.byID[uint32(id)]; dup {
+		return nil, fmt.Errorf("duplicate message identifier %s", f[1])
+	}
+	m := &Message{
+		ID:       uint32(id) &^ extendedFlag,
+		Extended: id&extendedFlag != 0,
+		Name:     f[2],
+		Size:     size,
+		Sender:   f[4],
+	}
+	if m.Extended && m.ID > 0x1FFFFFFF || !m.Extended && m.ID > 0x7FF {
+		return nil, fmt.Errorf("message %s has invalid identifier %s", f[2], f[1])
+	}
+	db.Messages = append(db.Messages, m)
+	db.byID[uint32(id)] = m
+	return m, nil
+}
+
+func (m *Message) parseSignal(text string) error {
+	f := signalLine.FindStringSubmatch(text)
+	if f == nil {
+		return fmt.Errorf("malformed signal %q", text)
+	}
+	s := &Signal{
+		Name:      f[1],
+		BigEndian: f[5] == "0",
+		Signed:    f[6] == "-",
+		Unit:      f[11],
+		Receivers: strings.FieldsFunc(f[12], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }),
+	}
+	if m.Signal(s.Name) != nil {
+		return fmt.Errorf("duplicate signal %s in message %s", s.Name, m.Name)
+	}
+	switch mux := f[2]; {
+	case mux == "M":
+		if m.mux != nil {
+			return fmt.Errorf("second multiplexer %s in message %s", s.Name, m.Name)
+		}
+		s.Multiplexer = true
+		m.mux = s
+	case mux != "":
+		v, err := strconv.ParseUint(strings.TrimSuffix(mux[1:], "M"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("multiplexer value %s of signal %s", mux, s.Name)
+		}
+		s.Multiplexed, s.MuxValue = true, v
+	}
+	s.Start, _ = strconv.Atoi(f[3])
+	s.Length, _ = strconv.Atoi(f[4])
+	var err error
+	for i, p := range []*float64{&s.Scale, &s.Offset, &s.Min, &s.Max} {
+		if *p, err = strconv.ParseFloat(strings.TrimSpace(f[7+i]), 64); err != nil {
+			return fmt.Errorf("number %q in signal %s", f[7+i], s.Name)
+		}
+	}
+	if s.Scale == 0 {
+		return fmt.Errorf("signal %s has scale 0", s.Name)
+	}
+	if s.Length < 1 || s.Length > 64 {
+		return fmt.Errorf("signal %s has invalid length %d", s.Name, s.Length)
+	}
+	if _, size := s.bits(); size > m.Size {
+		return fmt.Errorf("signal %s does not fit in the %d bytes of message %s", s.Name, m.Size, m.Name)
+	}
+	m.Signals = append(m.Signals, s)
+	return nil
+}
+
+func (db *Database) parseValueType(text string) error {
+	f := valueTypeLine.FindStringSubmatch(text)
+	if f == nil {
+		return fmt.Errorf("malformed signal value type %q", text)
+	}
+	id, _ := strconv.ParseUint(f[1], 10, 32)
+	m, ok := db.byID[uint32(id)]
+	if !ok {
+		return fmt.Errorf("value type for unknown message %s", f[1])
+	}
+	s := m.Signal(f[2])
+	if s == nil {
+		return fmt.Errorf("value type for unknown signal %s in message %s", f[2], m.Name)
+	}
+	s.Type = ValueType(f[3][0] - '0')
+	if s.Type == Float32 && s.Length != 32 || s.Type == Float64 && s.Length != 64 {
+		return fmt.Errorf("signal %s of %d bits cannot hold a %v", s.Name, s.Length, s.Type)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package can
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDBC = `VERSION ""
+
+NS_ :
+	CM_
+	SIG_VALTYPE_
+
+BS_:
+
+BU_: ECU Gateway Dash
+
+BO_ 256 Engine: 8 ECU
+ SG_ EngineSpeed : 0|16@1+ (0.25,0) [0|16383.75] "rpm" Dash,Gateway
+ SG_ Coolant : 16|8@1- (1,-40) [-168|87] "degC" Dash
+ SG_ Pressure : 31|12@0+ (0.1,0) [0|409.5] "kPa" Dash
+ SG_ Throttle : 35|4@0+ (10,0) [0|150] "%" Dash
+ SG_ Flags : 40|3@1+ (1,0) [0|7] "" Vector__XXX
+
+BO_ 2566839550 Diag: 8 Gateway
+ SG_ Mode M : 0|8@1+ (1,0) [0|255] "" Dash
+ SG_ Voltage m1 : 8|16@1+ (0.001,0) [0|65.535] "V" Dash
+ SG_ Current m2 : 8|16@1- (0.01,0) [-327.68|327.67] "A" Dash
+
+BO_ 512 Wide: 64 ECU
+ SG_ Level : 32|32@1+ (1,0) [0|0] "m" Dash
+ SG_ Tail : 499|12@0+ (1,0) [0|4095] "" Dash
+ SG_ Far : 480|12@1+ (1,0) [0|4095] "" Dash
+
+BO_ 3221225472 VECTOR__INDEPENDENT_SIG_MSG: 0 Vector__XXX
+ SG_ Orphan : 0|8@1+ (1,0) [0|0] "" Vector__XXX
+
+CM_ SG_ 256 EngineSpeed "Crankshaft speed.";
+BA_DEF_ BO_ "GenMsgCycleTime" INT 0 10000;
+SIG_VALTYPE_ 512 Level : 1;
+`
+
+func testDatabase(t *testing.T) *Database {
+	t.Helper()
+	db, err := Parse(strings.NewReader(testDBC))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	return db
+}
+
+func TestParse(t *testing.T) {
+	db := testDatabase(t)
+	if len(db.Messages) != 3 {
+		t.Fatalf("got %d messages, want 3", len(db.Messages))
+	}
+	m, ok := db.Message(0x80000000 | 0x18FEDCFE)
+	if !ok || m.Name != "Diag" || !m.Extended || m.ID != 0x18FEDCFE {
+		t.Fatalf("extended message: got=%+v", m)
+	}
+	if m, _ := db.MessageByName("Engine"); m == nil || m.ID != 256 || m.Sender != "ECU" {
+		t.Fatalf("MessageByName: got=%+v", m)
+	}
+	want := &Signal{
+		Name: "Coolant", Start: 16, Length: 8, Signed: true, Scale: 1, Offset: -40,
+		Min: -168, Max: 87, Unit: "degC", Receivers: []string{"Dash"},
+	}
+	if got := db.Messages[0].Signal("Coolant"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("signal: got=%+v, want=%+v", got, want)
+	}
+	if got := db.Messages[0].Signal("EngineSpeed").Receivers; !reflect.DeepEqual(got, []string{"Dash", "Gateway"}) {
+		t.Fatalf("receivers: got=%q", got)
+	}
+	if s := db.Messages[2].Signal("Level"); s.Type != Float32 {
+		t.Fatalf("value type: got=%v", s.Type)
+	}
+}
+
+func TestMessageDecode(t *testing.T) {
+	db := testDatabase(t)
+	engine, _ := db.Message(256)
+	p := []byte{0x40, 0x1F, 0xEC, 0x12, 0x34, 0x05, 0x00, 0x00}
+	got, err := engine.Decode(p)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	want := map[string]float64{
+		"EngineSpeed": 0x1F40 * 0.25, // 2000 rpm
+		"Coolant":     -20 - 40,      // raw 0xEC is -20
+		"Pressure":    float64(0x123) * 0.1,
+		"Throttle":    40,
+		"Flags":       5,
+	}
+	for name, v := range want {
+		if math.Abs(got[name]-v) > 1e-9 {
+			t.Errorf("%s: got=%v, want=%v", name, got[name], v)
+		}
+	}
+
+	back, err := engine.Encode(got)
+	if err != nil || !bytes.Equal(back, p) {
+		t.Fatalf("Encode: got=% x err=%v, want=% x", back, err, p)
+	}
+}
+
+func TestMessageMultiplexed(t *testing.T) {
+	db := testDatabase(t)
+	diag, _ := db.MessageByName("Diag")
+	for _, tt := range []struct {
+		values map[string]float64
+		p      []byte
+	}{
+		{map[string]float64{"Mode": 1, "Voltage": 12.5}, []byte{1, 0xD4, 0x30, 0, 0, 0, 0, 0}},
+		{map[string]float64{"Mode": 2, "Current": -1.5}, []byte{2, 0x6A, 0xFF, 0, 0, 0, 0, 0}},
+		{map[string]float64{"Mode": 3}, []byte{3, 0, 0, 0, 0, 0, 0, 0}},
+	} {
+		p, err := diag.Encode(tt.values)
+		if err != nil || !bytes.Equal(p, tt.p) {
+			t.Fatalf("Encode(%v): got=% x err=%v, want=% x", tt.values, p, err, tt.p)
+		}
+		got, err := diag.Decode(p)
+		if err != nil || !reflect.DeepEqual(got, tt.values) {
+			t.Fatalf("Decode: got=%v err=%v, want=%v", got, err, tt.values)
+		}
+	}
+}
+
+func TestMessageFD(t *testing.T) {
+	db := testDatabase(t)
+	wide, _ := db.Message(512)
+	values := map[string]float64{"Level": 1.5, "Tail": 0xABC, "Far": 0x123}
+	p, err := wide.Encode(values)
+	if err != nil || len(p) != 64 {
+		t.Fatalf("Encode: %d bytes err=%v", len(p), err)
+	}
+	if !bytes.Equal(p[4:8], []byte{0x00, 0x00, 0xC0, 0x3F}) || !bytes.Equal(p[60:], []byte{0x23, 0x01, 0x0A, 0xBC}) {
+		t.Fatalf("Encode: got=% x", p)
+	}
+	got, err := wide.Decode(p)
+	if err != nil || !reflect.DeepEqual(got, values) {
+		t.Fatalf("Decode: got=%v err=%v", got, err)
+	}
+	if _, err := wide.Decode(p[:8]); !errors.Is(err, ErrPayload) {
+		t.Fatalf("classic payload: got err=%v, want ErrPayload", err)
+	}
+}
+
+func TestMessageErrors(t *testing.T) {
+	db := testDatabase(t)
+	engine, _ := db.Message(256)
+	for name, values := range map[string]map[string]float64{
+		"Unsigned": {"EngineSpeed": -1},
+		"Overflow": {"EngineSpeed": 16384},
+		"Signed":   {"Coolant": 90},
+		"NaN":      {"Flags": math.NaN()},
+	} {
+		if _, err := engine.Encode(values); !errors.Is(err, ErrRange) {
+			t.Errorf("%s: got err=%v, want ErrRange", name, err)
+		}
+	}
+	if _, err := engine.Encode(map[string]float64{"Speed": 1}); !errors.Is(err, ErrSignal) {
+		t.Errorf("unknown signal: got err=%v, want ErrSignal", err)
+	}
+	if _, err := engine.Decode(make([]byte, 4)); !errors.Is(err, ErrPayload) {
+		t.Errorf("short payload: got err=%v, want ErrPayload", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, dbc := range map[string]string{
+		"Orphan":     " SG_ A : 0|8@1+ (1,0) [0|0] \"\" X",
+		"Malformed":  "BO_ 1 A: 8 X\n SG_ B : 0|8@1+ (1,0) \"\" X",
+		"Size":       "BO_ 1 A: 9 X",
+		"Identifier": "BO_ 4096 A: 8 X",
+		"Duplicate":  "BO_ 1 A: 8 X\nBO_ 1 B: 8 X",
+		"Fit":        "BO_ 1 A: 2 X\n SG_ B : 7|16@1+ (1,0) [0|0] \"\" X",
+		"Scale":      "BO_ 1 A: 8 X\n SG_ B : 0|8@1+ (0,0) [0|0] \"\" X",
+		"ValueType":  "BO_ 1 A: 8 X\n SG_ B : 0|16@1+ (1,0) [0|0] \"\" X\nSIG_VALTYPE_ 1 B : 1;",
+	} {
+		if _, err := Parse(strings.NewReader(dbc)); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%s: got err=%v, want ErrSyntax", name, err)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package can
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// Signal is a value packed into the payload of a message.
+type Signal struct {
+	Name      string
+	Start     int       // Start bit as written in the DBC file: the LSB for Intel, the MSB for Motorola signals
+	Length    int       // Length in bits, 1 to 64
+	BigEndian bool      // Motorola byte order; Intel if false
+	Signed    bool      // Two's complement integer
+	Type      ValueType // Type of the raw value
+	Scale     float64   // Physical value = raw * Scale + Offset
+	Offset    float64
+	Min, Max  float64 // Physical range from the DBC file, informational only
+	Unit      string
+	Receivers []string
+
+	Multiplexer bool // The signal selects which multiplexed signals are present
+	Multiplexed bool // The signal is present only when the multiplexer equals MuxValue
+	MuxValue    uint64
+}
+
+// bits returns the position of the signal's first bit in the payload for a
+// bitflux.BitReader of the signal's byte order, and the payload size it needs in bytes.
+func (s *Signal) bits() (start, size int) {
+	if s.BigEndian {
+		start = motorolaStart(s.Start)
+	} else {
+		start = s.Start
+	}
+	return start, (start + s.Length + 7) / 8
+}
+
+// check returns an error wrapping ErrPayload if p is too short for the signal or too large.
+func (s *Signal) check(p []byte) (int, error) {
+	start, size := s.bits()
+	if len(p) > MaxData || len(p) < size {
+		return 0, fmt.Errorf("%w: %d bytes for signal %q", ErrPayload, len(p), s.Name)
+	}
+	return start, nil
+}
+
+// Raw extracts the raw bits of the signal from p.
+func (s *Signal) Raw(p []byte) (uint64, error) {
+	start, err := s.check(p)
+	if err != nil {
+		return 0, err
+	}
+	r := bitflux.BitReader{Buf: p, BigEndian: s.BigEndian, Pos: start}
+	raw := r.Bits(s.Length)
+	return raw, r.Err
+}
+
+// SetRaw stores the raw bits of the signal in p, leaving the other bits unchanged.
+// It returns an error wrapping ErrRange if raw does not fit in Length bits.
+func (s *Signal) SetRaw(p []byte, raw uint64) error {
+	start, err := s.check(p)
+	if err != nil {
+		return err
+	}
+	if s.Length < 64 && raw>>s.Length != 0 {
+		return fmt.Errorf("%w: raw value %d for signal %q", ErrRange, raw, s.Name)
+	}
+	w := bitflux.BitWriter{Buf: p, BigEndian: s.BigEndian, Pos: start}
+	w.Bits(s.Length, raw)
+	return w.Err
+}
+
+// Decode extracts the physical value of the signal from p.
+func (s *Signal) Decode(p []byte) (float64, error) {
+	raw, err := s.Raw(p)
+	if err != nil {
+		return 0, err
+	}
+	var v float64
+	switch {
+	case s.Type == Float32:
+		v = float64(math.Float32frombits(uint32(raw)))
+	case s.Type == Float64:
+		v = math.Float64frombits(raw)
+	case s.Signed:
+		if s.Length < 64 && raw>>(s.Length-1)&1 != 0 {
+			raw |= ^uint64(0) << s.Length
+		}
+		v = float64(int64(raw))
+	default:
+		v = float64(raw)
+	}
+	return v*s.Scale + s.Offset, nil
+}
+
+// Encode stores the physical value v of the signal in p, rounding integer signals to the
+// nearest raw value. It returns an error wrapping ErrRange if v does not fit in the signal.
+func (s *Signal) Encode(p []byte, v float64) error {
+	r := (v - s.Offset) / s.Scale
+	var raw uint64
+	switch {
+	case s.Type == Float32:
+		raw = uint64(math.Float32bits(float32(r)))
+	case s.Type == Float64:
+		raw = math.Float64bits(r)
+	case s.Signed:
+		r = math.Round(r)
+		limit := math.Ldexp(1, s.Length-1)
+		if !(r >= -limit && r < limit) {
+			return fmt.Errorf("%w: %v for signal %q", ErrRange, v, s.Name)
+		}
+		raw = uint64(int64(r))
+		if s.Length < 64 {
+			raw &= 1<<s.Length - 1
+		}
+	default:
+		r = math.Round(r)
+		if !(r >= 0 && r < math.Ldexp(1, s.Length)) {
+			return fmt.Errorf("%w: %v for signal %q", ErrRange, v, s.Name)
+		}
+		raw = uint64(r)
+	}
+	return s.SetRaw(p, raw)
+}
+
+// Message is a CAN frame layout defined in a DBC file.
+type Message struct {
+	ID       uint32 // Identifier, without the extended frame flag
+	Extended bool   // 29-bit identifier
+	Name     string
+	Size     int // Payload size in bytes
+	Sender   string
+	Signals  []*Signal // Signals in declaration order
+
+	mux *Signal
+}
+
+// Signal returns the named signal, or nil if the message has none.
+func (m *Message) Signal(name string) *Signal {
+	for _, s := range m.Signals {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Decode extracts the physical values of the signals present in p, keyed by signal name.
+// Multiplexed signals are only decoded when the multiplexer selects them.
+func (m *Message) Decode(p []byte) (map[string]float64, error) {
+	var sel uint64
+	if m.mux != nil {
+		var err error
+		if sel, err = m.mux.Raw(p); err != nil {
+			return nil, err
+		}
+	}
+	values := make(map[string]float64, len(m.Signals))
+	for _, s := range m.Signals {
+		if s.Multiplexed && s.MuxValue != sel {
+			continue
+		}
+		v, err := s.Decode(p)
+		if err != nil {
+			return nil, err
+		}
+		values[s.Name] = v
+	}
+	return values, nil
+}
+
+// Encode returns a payload of Size bytes holding values. Signals missing from values
+// have a raw value of zero, as do multiplexed signals the multiplexer does not select.
+// It returns ErrSignal for names not in the message and ErrRange for values that do not fit.
+func (m *Message) Encode(values map[string]float64) ([]byte, error) {
+	for name := range values {
+		if m.Signal(name) == nil {
+			return nil, fmt.Errorf("%w: %q in message %s", ErrSignal, name, m.Name)
+		}
+	}
+	p := make([]byte, m.Size)
+	var sel uint64
+	if m.mux != nil {
+		if err := m.mux.Encode(p, values[m.mux.Name]); err != nil {
+			return nil, err
+		}
+		sel, _ = m.mux.Raw(p)
+	}
+	for _, s := range m.Signals {
+		v, ok := values[s.Name]
+		if !ok || s == m.mux || s.Multiplexed && s.MuxValue != sel {
+			continue
+		}
+		if err := s.Encode(p, v); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,16 @@
+module github.com/jon-ski/bitflux/cmd/bitflux
+
+go 1.26.0
+
+require (
+	github.com/jon-ski/bitflux v0.0.0
+	golang.org/x/tools v0.51.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sync v0.23.0 // indirect
+)
+
+replace github.com/jon-ski/bitflux => ../..
@@ -0,0 +1,117 @@
+// Command bitflux provides tooling for users of the bitflux package.
+//
+// Usage:
+//
+//	bitflux migrate [-fix] [-diff] packages...
+//	bitflux encode -schema file [-hex] [-o file] [records...]
+//
+// The migrate command reports calls to the deprecated Buffer, Reader, Le and Be
+// APIs. With -fix it rewrites them into the equivalent EncLE/EncBE and
+// DecLE/DecBE code; -diff prints the changes instead of applying them.
+//
+// The encode command reads YAML or JSON records from the named files, or from
+// standard input, and writes their binary form as defined by the schema file,
+// computing length fields and checksums. With -hex it prints one line of hex
+// per record instead. See package schema for the schema format.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jon-ski/bitflux/cmd/bitflux/migrate"
+	"github.com/jon-ski/bitflux/cmd/bitflux/schema"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+const usage = `usage: bitflux migrate [-fix] [-diff] packages...
+       bitflux encode -schema file [-hex] [-o file] [records...]`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "migrate":
+		os.Args = os.Args[1:]
+		singlechecker.Main(migrate.Analyzer)
+	case "encode":
+		if err := encode(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "bitflux encode:", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// encode runs the encode command with args.
+func encode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	schemaFile := fs.String("schema", "", "schema `file` describing the records")
+	hexOut := fs.Bool("hex", false, "print one line of hex per record")
+	outFile := fs.String("o", "", "write the output to `file` instead of standard output")
+	fs.Parse(args)
+	if *schemaFile == "" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*schemaFile)
+	if err != nil {
+		return err
+	}
+	s, err := schema.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	var recs []map[string]any
+	if fs.NArg() == 0 {
+		if recs, err = schema.Records(os.Stdin); err != nil {
+			return fmt.Errorf("stdin: %w", err)
+		}
+	}
+	for _, name := range fs.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		r, err := schema.Records(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		recs = append(recs, r...)
+	}
+
+	var out io.WriteCloser = os.Stdout
+	if *outFile != "" {
+		if out, err = os.Create(*outFile); err != nil {
+			return err
+		}
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	for i, rec := range recs {
+		p, err := s.Encode(rec)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if *hexOut {
+			fmt.Fprintln(w, hex.EncodeToString(p))
+		} else {
+			w.Write(p)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,341 @@
+// Package migrate provides an analyzer that rewrites calls to the deprecated
+// Buffer, Reader, Le and Be APIs of bitflux into the equivalent EncLE/EncBE and
+// DecLE/DecBE code.
+//
+// Calls whose results are discarded become a single encoder or decoder call:
+//
+//	buf.WriteLUint16(v)         =>  bitflux.NewEncLE(buf).U16(v)
+//	bitflux.Le.WriteUint32(v)   =>  bitflux.AppendU32LE(nil, v)
+//
+// Calls whose results are used are wrapped in a function literal that returns
+// the same values, so the surrounding error handling is unchanged:
+//
+//	v, err := r.ReadBFloat32()  =>  v, err := func() (float32, error) {
+//	                                        d := bitflux.NewDecBE(r)
+//	                                        v := d.F32()
+//	                                        return v, d.Err
+//	                                }()
+//
+// Buffer and Reader keep their sticky errors because the encoders and decoders
+// read and write through them.
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// PkgPath is the import path of the bitflux package.
+const PkgPath = "github.com/jon-ski/bitflux"
+
+// Analyzer reports calls to deprecated bitflux APIs and suggests their replacement.
+var Analyzer = &analysis.Analyzer{
+	Name:     "bitfluxmigrate",
+	Doc:      "rewrite deprecated bitflux Buffer, Reader, Le and Be calls to EncLE/EncBE and DecLE/DecBE",
+	URL:      "https://pkg.go.dev/github.com/jon-ski/bitflux/cmd/bitflux/migrate",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// kind is the shape of a replacement.
+type kind int
+
+const (
+	encode  kind = iota // encoder method, result is an error
+	decode              // decoder method, results are a value and an error
+	append_             // Append function, result is a []byte
+)
+
+// target is the replacement for a deprecated method.
+type target struct {
+	kind   kind
+	order  string // "LE" or "BE"
+	method string // encoder/decoder method, or Append function name
+}
+
+// bufferMethods maps the serialization methods of Buffer and Reader.
+var bufferMethods = map[string]target{
+	"WriteByte":     {encode, "LE", "U8"},
+	"WriteUint8":    {encode, "LE", "U8"},
+	"WriteLUint16":  {encode, "LE", "U16"},
+	"WriteLUint32":  {encode, "LE", "U32"},
+	"WriteLUint64":  {encode, "LE", "U64"},
+	"WriteLFloat32": {encode, "LE", "F32"},
+	"WriteLFloat64": {encode, "LE", "F64"},
+	"WriteBUint16":  {encode, "BE", "U16"},
+	"WriteBUint32":  {encode, "BE", "U32"},
+	"WriteBUint64":  {encode, "BE", "U64"},
+	"WriteBFloat32": {encode, "BE", "F32"},
+	"WriteBFloat64": {encode, "BE", "F64"},
+	"WriteBinary":   {encode, "LE", "Marshal"},
+	"WriteString":   {encode, "LE", "Write"},
+	"ReadByte":      {decode, "LE", "U8"},
+	"ReadUint8":     {decode, "LE", "U8"},
+	"ReadLUint16":   {decode, "LE", "U16"},
+	"ReadLUint32":   {decode, "LE", "U32"},
+	"ReadLUint64":   {decode, "LE", "U64"},
+	"ReadLFloat32":  {decode, "LE", "F32"},
+	"ReadLFloat64":  {decode, "LE", "F64"},
+	"ReadBUint16":   {decode, "BE", "U16"},
+	"ReadBUint32":   {decode, "BE", "U32"},
+	"ReadBUint64":   {decode, "BE", "U64"},
+	"ReadBFloat32":  {decode, "BE", "F32"},
+	"ReadBFloat64":  {decode, "BE", "F64"},
+	"ReadBinary":    {decode, "LE", "Unmarshal"},
+}
+
+// codecMethod maps a method of Le or Be, such as WriteUint16 or ReadFloat32.
+func codecMethod(name, order string) (target, bool) {
+	var k kind
+	switch {
+	case strings.HasPrefix(name, "Write"):
+		k, name = append_, strings.TrimPrefix(name, "Write")
+	case strings.HasPrefix(name, "Read"):
+		k, name = decode, strings.TrimPrefix(name, "Read")
+	default:
+		return target{}, false
+	}
+	var m string
+	switch name {
+	case "Uint8", "Uint16", "Uint32", "Uint64":
+		m = "U" + strings.TrimPrefix(name, "Uint")
+	case "Int8", "Int16", "Int32", "Int64":
+		m = "I" + strings.TrimPrefix(name, "Int")
+	case "Float32", "Float64":
+		m = "F" + strings.TrimPrefix(name, "Float")
+	default:
+		return target{}, false
+	}
+	if k == append_ {
+		m = "Append" + m
+		if m != "AppendU8" && m != "AppendI8" {
+			m += order
+		}
+	}
+	return target{k, order, m}, true
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if pass.Pkg.Path() == PkgPath {
+		return nil, nil
+	}
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	for cur := range ins.Root().Preorder((*ast.CallExpr)(nil)) {
+		call := cur.Node().(*ast.CallExpr)
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		s, ok := pass.TypesInfo.Selections[sel]
+		if !ok || s.Kind() != types.MethodVal {
+			continue
+		}
+		fn := s.Obj().(*types.Func)
+		if fn.Pkg() == nil || fn.Pkg().Path() != PkgPath {
+			continue
+		}
+		recv := s.Recv()
+		ptr := false
+		if p, ok := recv.(*types.Pointer); ok {
+			recv, ptr = p.Elem(), true
+		}
+		named, ok := recv.(*types.Named)
+		if !ok {
+			continue
+		}
+
+		var t target
+		switch tn := named.Obj().Name(); tn {
+		case "Buffer", "Reader":
+			if t, ok = bufferMethods[fn.Name()]; !ok {
+				continue
+			}
+			if tn == "Reader" && t.kind != decode {
+				continue
+			}
+		case "le", "be":
+			if t, ok = codecMethod(fn.Name(), strings.ToUpper(tn)); !ok {
+				continue
+			}
+		default:
+			continue
+		}
+
+		file := enclosingFile(pass, call)
+		stmt := isExprStmt(cur.Parent().Node(), call)
+		d := analysis.Diagnostic{
+			Pos:     call.Pos(),
+			End:     call.End(),
+			Message: fmt.Sprintf("%s.%s is deprecated", named.Obj().Name(), fn.Name()),
+		}
+		if q, ok := qualifier(file); ok {
+			var text string
+			switch {
+			case t.kind == append_:
+				text = appendCall(pass.Fset, q, t, call)
+			case named.Obj().Name() == "le" || named.Obj().Name() == "be":
+				// Le.ReadX(r) and Be.ReadX(r) read from their argument.
+				text = rewrite(pass.Fset, q, t, call, call.Args[0], nil, false, stmt, fn.Signature())
+			default:
+				text = rewrite(pass.Fset, q, t, call, sel.X, call.Args, !ptr, stmt, fn.Signature())
+			}
+			d.Message += fmt.Sprintf(", use %s", suggestion(q, t))
+			d.SuggestedFixes = []analysis.SuggestedFix{{
+				Message:   "Rewrite to " + suggestion(q, t),
+				TextEdits: []analysis.TextEdit{{Pos: call.Pos(), End: call.End(), NewText: []byte(text)}},
+			}}
+		}
+		pass.Report(d)
+	}
+	return nil, nil
+}
+
+// suggestion names the replacement API, such as bitflux.EncLE.U16.
+func suggestion(q string, t target) string {
+	switch t.kind {
+	case encode:
+		return q + "Enc" + t.order + "." + t.method
+	case decode:
+		return q + "Dec" + t.order + "." + t.method
+	}
+	return q + t.method
+}
+
+// rewrite returns the replacement for an encoder or decoder call. rw is the
+// io.Reader or io.Writer to wrap and args the arguments of the new method; addr
+// reports whether the address of rw must be taken. If stmt is set, the results
+// of the call are discarded.
+func rewrite(fset *token.FileSet, q string, t target, call *ast.CallExpr, rw ast.Expr, argExprs []ast.Expr, addr, stmt bool, sig *types.Signature) string {
+	args := make([]string, len(argExprs))
+	for i, a := range argExprs {
+		args[i] = node(fset, a)
+	}
+	src := node(fset, rw)
+	if addr {
+		src = "&" + paren(rw, src)
+	}
+	if t.method == "Write" {
+		args[0] = "[]byte(" + args[0] + ")"
+	}
+
+	ctor := q + "NewEnc" + t.order
+	if t.kind == decode {
+		ctor = q + "NewDec" + t.order
+	}
+	invoke := t.method + "(" + strings.Join(args, ", ") + ")"
+	if stmt {
+		return ctor + "(" + src + ")." + invoke
+	}
+
+	used := idents(call)
+	v := fresh(used, "d", "dec")
+	if t.kind == encode {
+		v = fresh(used, "e", "enc")
+	}
+	var b strings.Builder
+	switch {
+	case t.kind == encode, t.method == "Unmarshal":
+		fmt.Fprintf(&b, "func() error {\n%s := %s(%s)\n%s.%s\nreturn %s.Err\n}()", v, ctor, src, v, invoke, v)
+	default:
+		r := fresh(used, "v", "val")
+		typ := types.TypeString(sig.Results().At(0).Type(), nil)
+		fmt.Fprintf(&b, "func() (%s, error) {\n%s := %s(%s)\n%s := %s.%s\nreturn %s, %s.Err\n}()",
+			typ, v, ctor, src, r, v, invoke, r, v)
+	}
+	return b.String()
+}
+
+// appendCall returns the replacement for Le.WriteX(v) or Be.WriteX(v).
+func appendCall(fset *token.FileSet, q string, t target, call *ast.CallExpr) string {
+	return q + t.method + "(nil, " + node(fset, call.Args[0]) + ")"
+}
+
+// qualifier returns the name bitflux is imported as in file, followed by a dot.
+func qualifier(file *ast.File) (string, bool) {
+	if file == nil {
+		return "", false
+	}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != PkgPath {
+			continue
+		}
+		switch {
+		case imp.Name == nil:
+			return "bitflux.", true
+		case imp.Name.Name == ".":
+			return "", true
+		case imp.Name.Name == "_":
+			return "", false
+		default:
+			return imp.Name.Name + ".", true
+		}
+	}
+	return "", false
+}
+
+func enclosingFile(pass *analysis.Pass, n ast.Node) *ast.File {
+	for _, f := range pass.Files {
+		if f.FileStart <= n.Pos() && n.Pos() < f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
+
+// isExprStmt reports whether call is used as a statement, discarding its results.
+func isExprStmt(parent ast.Node, call *ast.CallExpr) bool {
+	s, ok := parent.(*ast.ExprStmt)
+	return ok && s.X == call
+}
+
+// idents returns the identifiers used in n.
+func idents(n ast.Node) map[string]bool {
+	m := make(map[string]bool)
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			m[id.Name] = true
+		}
+		return true
+	})
+	return m
+}
+
+// fresh returns the first of names, or a numbered variant, that is not in used.
+func fresh(used map[string]bool, names ...string) string {
+	for _, n := range names {
+		if !used[n] {
+			return n
+		}
+	}
+	for i := 1; ; i++ {
+		if n := names[0] + strconv.Itoa(i); !used[n] {
+			return n
+		}
+	}
+}
+
+// paren wraps src in parentheses if taking the address of e requires it.
+func paren(e ast.Expr, src string) string {
+	switch e.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.ParenExpr:
+		return src
+	}
+	return "(" + src + ")"
+}
+
+func node(fset *token.FileSet, n ast.Node) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, fset, n)
+	return b.String()
+}
@@ -0,0 +1,12 @@
+package migrate_test
+
+import (
+	"testing"
+
+	"github.com/jon-ski/bitflux/cmd/bitflux/migrate"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), migrate.Analyzer, "a")
+}
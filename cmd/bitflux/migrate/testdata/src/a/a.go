@@ -0,0 +1,39 @@
+package a
+
+import (
+	"encoding"
+	"io"
+
+	"github.com/jon-ski/bitflux"
+)
+
+func writes(buf *bitflux.Buffer, m encoding.BinaryMarshaler) error {
+	buf.WriteLUint16(1)                            // want `Buffer.WriteLUint16 is deprecated, use bitflux.EncLE.U16`
+	buf.WriteString("hi")                          // want `Buffer.WriteString is deprecated`
+	if err := buf.WriteBFloat32(1.5); err != nil { // want `Buffer.WriteBFloat32 is deprecated`
+		return err
+	}
+	e := 7
+	_ = buf.WriteLUint16(uint16(e)) // want `Buffer.WriteLUint16 is deprecated`
+	return buf.WriteBinary(m)       // want `Buffer.WriteBinary is deprecated, use bitflux.EncLE.Marshal`
+}
+
+func reads(buf bitflux.Buffer, r *bitflux.Reader, u encoding.BinaryUnmarshaler) (float64, error) {
+	v, err := buf.ReadBUint32() // want `Buffer.ReadBUint32 is deprecated`
+	_, _ = v, err
+	r.ReadByte()                                 // want `Reader.ReadByte is deprecated`
+	if err := buf.ReadBinary(u, 4); err != nil { // want `Buffer.ReadBinary is deprecated`
+		return 0, err
+	}
+	return r.ReadLFloat64() // want `Reader.ReadLFloat64 is deprecated`
+}
+
+func codecs(r io.Reader) []byte {
+	b := bitflux.Le.WriteUint32(0x01020304)      // want `le.WriteUint32 is deprecated, use bitflux.AppendU32LE`
+	b = append(b, bitflux.Le.WriteInt8(-1)...)   // want `le.WriteInt8 is deprecated`
+	b = append(b, bitflux.Be.WriteFloat64(2)...) // want `be.WriteFloat64 is deprecated`
+	x, _ := bitflux.Le.ReadUint16(r)             // want `le.ReadUint16 is deprecated, use bitflux.DecLE.U16`
+	bitflux.Be.ReadInt32(r)                      // want `be.ReadInt32 is deprecated`
+	_ = x
+	return b
+}
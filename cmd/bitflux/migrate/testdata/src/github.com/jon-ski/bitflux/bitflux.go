@@ -0,0 +1,66 @@
+// Package bitflux is a stub of the deprecated and replacement APIs.
+package bitflux
+
+import (
+	"encoding"
+	"io"
+)
+
+var (
+	Le = new(le)
+	Be = new(be)
+)
+
+type Buffer struct{}
+
+func (b *Buffer) Write(p []byte) (int, error)                          { return len(p), nil }
+func (b *Buffer) Read(p []byte) (int, error)                           { return 0, io.EOF }
+func (b *Buffer) Err() error                                           { return nil }
+func (b *Buffer) WriteLUint16(v uint16) error                          { return nil }
+func (b *Buffer) WriteBFloat32(v float32) error                        { return nil }
+func (b *Buffer) WriteString(v string) error                           { return nil }
+func (b *Buffer) WriteBinary(m encoding.BinaryMarshaler) error         { return nil }
+func (b *Buffer) ReadBUint32() (uint32, error)                         { return 0, nil }
+func (b *Buffer) ReadBinary(u encoding.BinaryUnmarshaler, n int) error { return nil }
+
+type Reader struct{}
+
+func (r *Reader) Read(p []byte) (int, error)     { return 0, io.EOF }
+func (r *Reader) ReadByte() (byte, error)        { return 0, nil }
+func (r *Reader) ReadLFloat64() (float64, error) { return 0, nil }
+
+type le struct{}
+
+func (le) WriteUint32(v uint32) []byte            { return nil }
+func (le) WriteInt8(v int8) []byte                { return nil }
+func (le) ReadUint16(r io.Reader) (uint16, error) { return 0, nil }
+
+type be struct{}
+
+func (be) WriteFloat64(v float64) []byte        { return nil }
+func (be) ReadInt32(r io.Reader) (int32, error) { return 0, nil }
+
+type EncLE struct{ Err error }
+type EncBE struct{ Err error }
+type DecLE struct{ Err error }
+type DecBE struct{ Err error }
+
+func NewEncLE(w io.Writer) *EncLE { return &EncLE{} }
+func NewEncBE(w io.Writer) *EncBE { return &EncBE{} }
+func NewDecLE(r io.Reader) *DecLE { return &DecLE{} }
+func NewDecBE(r io.Reader) *DecBE { return &DecBE{} }
+
+func (e *EncLE) U16(v uint16)                                  {}
+func (e *EncLE) Write(p []byte)                                {}
+func (e *EncLE) Marshal(m encoding.BinaryMarshaler)            {}
+func (e *EncBE) F32(v float32)                                 {}
+func (d *DecLE) U8() uint8                                     { return 0 }
+func (d *DecLE) U16() uint16                                   { return 0 }
+func (d *DecLE) F64() float64                                  { return 0 }
+func (d *DecLE) Unmarshal(u encoding.BinaryUnmarshaler, n int) {}
+func (d *DecBE) U32() uint32                                   { return 0 }
+func (d *DecBE) I32() int32                                    { return 0 }
+
+func AppendU32LE(b []byte, v uint32) []byte  { return b }
+func AppendI8(b []byte, v int8) []byte       { return b }
+func AppendF64BE(b []byte, v float64) []byte { return b }
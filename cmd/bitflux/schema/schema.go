@@ -0,0 +1,507 @@
+// Package schema describes binary records in YAML or JSON and encodes records
+// given as YAML or JSON values into their binary form with bitflux.EncLE and
+// bitflux.EncBE.
+//
+// A schema lists the fields of a record in wire order:
+//
+//	name: telemetry
+//	byteOrder: big
+//	fields:
+//	  - {name: sync, type: u16, value: 0xEB90}
+//	  - {name: length, type: u8, lengthOf: [id, payload]}
+//	  - {name: id, type: u16}
+//	  - {name: payload, type: bytes}
+//	  - {name: crc, type: u16, byteOrder: little, checksum: crc16-modbus, over: [length, id, payload]}
+//
+// Fields are numbers (u8 to u64, i8 to i64, f32 and f64), bytes or strings.
+// Bytes and strings have a fixed size, padded with zeros, or the size of their
+// value when size is 0. Bytes are written as hex strings or lists of numbers.
+//
+// Length fields hold the encoded size of the fields they list plus adjust.
+// Checksum fields hold the checksum of the fields they cover, all preceding
+// fields by default. Records may set computed fields to craft invalid packets.
+package schema
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jon-ski/bitflux"
+	"github.com/jon-ski/bitflux/modbus"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the layout of a record.
+type Schema struct {
+	Name      string  `yaml:"name"`
+	ByteOrder string  `yaml:"byteOrder"` // "big" (default) or "little"
+	Fields    []Field `yaml:"fields"`
+
+	index map[string]int
+}
+
+// Field is one field of a record.
+type Field struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`      // u8, u16, u32, u64, i8, i16, i32, i64, f32, f64, bytes or string
+	ByteOrder string   `yaml:"byteOrder"` // Overrides the schema's byte order
+	Size      int      `yaml:"size"`      // Size of bytes and string fields; 0 means the size of the value
+	Value     any      `yaml:"value"`     // Value used when a record does not set the field
+	LengthOf  []string `yaml:"lengthOf"`  // Fields whose encoded size the field holds
+	Adjust    int      `yaml:"adjust"`    // Added to the size of the LengthOf fields
+	Checksum  string   `yaml:"checksum"`  // Checksum algorithm: sum8, xor8, crc16-modbus or crc32
+	Over      []string `yaml:"over"`      // Fields covered by the checksum; all preceding fields if empty
+
+	little bool
+	over   []int
+}
+
+// checksum is a checksum algorithm.
+type checksum struct {
+	size int // Size of the checksum in bytes
+	sum  func(p []byte) uint64
+}
+
+var checksums = map[string]checksum{
+	"sum8": {1, func(p []byte) uint64 {
+		var v byte
+		for _, c := range p {
+			v += c
+		}
+		return uint64(v)
+	}},
+	"xor8": {1, func(p []byte) uint64 {
+		var v byte
+		for _, c := range p {
+			v ^= c
+		}
+		return uint64(v)
+	}},
+	"crc16-modbus": {2, func(p []byte) uint64 { return uint64(modbus.CRC16(p)) }},
+	"crc32":        {4, func(p []byte) uint64 { return uint64(crc32.ChecksumIEEE(p)) }},
+}
+
+// numberSizes holds the encoded size of the numeric types.
+var numberSizes = map[string]int{
+	"u8": 1, "u16": 2, "u32": 4, "u64": 8,
+	"i8": 1, "i16": 2, "i32": 4, "i64": 8,
+	"f32": 4, "f64": 8,
+}
+
+// Parse parses a YAML or JSON schema and checks that it is consistent.
+func Parse(data []byte) (*Schema, error) {
+	s := new(Schema)
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true)
+	if err := d.Decode(s); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	if err := s.init(); err != nil {
+		return nil, fmt.Errorf("schema %s: %w", s.Name, err)
+	}
+	return s, nil
+}
+
+// init checks the schema and resolves field references.
+func (s *Schema) init() error {
+	little, err := parseByteOrder(s.ByteOrder, false)
+	if err != nil {
+		return err
+	}
+	if len(s.Fields) == 0 {
+		return errors.New("no fields")
+	}
+	s.index = make(map[string]int, len(s.Fields))
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if f.Name == "" {
+			return fmt.Errorf("field %d has no name", i)
+		}
+		if _, dup := s.index[f.Name]; dup {
+			return fmt.Errorf("duplicate field %q", f.Name)
+		}
+		s.index[f.Name] = i
+		if f.little, err = parseByteOrder(f.ByteOrder, little); err != nil {
+			return fmt.Errorf("field %q: %w", f.Name, err)
+		}
+	}
+	for i := range s.Fields {
+		if err := s.initField(i); err != nil {
+			return fmt.Errorf("field %q: %w", s.Fields[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// initField checks field i and resolves the fields it refers to.
+func (s *Schema) initField(i int) error {
+	f := &s.Fields[i]
+	size, number := numberSizes[f.Type]
+	switch {
+	case !number && f.Type != "bytes" && f.Type != "string":
+		return fmt.Errorf("unknown type %q", f.Type)
+	case f.Size < 0 || number && f.Size != 0:
+		return fmt.Errorf("invalid size %d", f.Size)
+	case f.LengthOf != nil && f.Checksum != "":
+		return errors.New("both lengthOf and checksum are set")
+	case (f.LengthOf != nil || f.Checksum != "") && (!number || f.Type[0] == 'f'):
+		return fmt.Errorf("computed field of type %s", f.Type)
+	case f.computed() && f.Value != nil:
+		return errors.New("computed field has a value")
+	case f.Over != nil && f.Checksum == "":
+		return errors.New("over is set without checksum")
+	}
+	for _, name := range f.LengthOf {
+		if _, ok := s.index[name]; !ok {
+			return fmt.Errorf("lengthOf: unknown field %q", name)
+		}
+	}
+	if f.Checksum == "" {
+		return nil
+	}
+	c, ok := checksums[f.Checksum]
+	if !ok {
+		return fmt.Errorf("unknown checksum %q", f.Checksum)
+	}
+	if size < c.size {
+		return fmt.Errorf("%s checksum does not fit in %s", f.Checksum, f.Type)
+	}
+	if f.Over == nil {
+		for j := range i {
+			f.over = append(f.over, j)
+		}
+		return nil
+	}
+	for _, name := range f.Over {
+		j, ok := s.index[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("over: unknown field %q", name)
+		case j == i:
+			return errors.New("checksum covers itself")
+		case j > i && s.Fields[j].Checksum != "":
+			return fmt.Errorf("over: checksum %q follows the field", name)
+		}
+		f.over = append(f.over, j)
+	}
+	return nil
+}
+
+func parseByteOrder(s string, def bool) (little bool, err error) {
+	switch s {
+	case "":
+		return def, nil
+	case "big":
+		return false, nil
+	case "little":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown byte order %q", s)
+}
+
+// computed reports whether the field is computed from other fields.
+func (f *Field) computed() bool { return f.LengthOf != nil || f.Checksum != "" }
+
+// Encode returns the binary form of rec, which maps field names to values.
+func (s *Schema) Encode(rec map[string]any) ([]byte, error) {
+	for name := range rec {
+		if _, ok := s.index[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	parts := make([][]byte, len(s.Fields))
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		v, ok := rec[f.Name]
+		switch {
+		case ok:
+		case f.computed():
+			parts[i] = make([]byte, numberSizes[f.Type])
+			continue
+		case f.Value != nil:
+			v = f.Value
+		default:
+			return nil, fmt.Errorf("missing field %q", f.Name)
+		}
+		p, err := f.encode(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		parts[i] = p
+	}
+
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if _, ok := rec[f.Name]; ok || f.LengthOf == nil {
+			continue
+		}
+		n := f.Adjust
+		for _, name := range f.LengthOf {
+			n += len(parts[s.index[name]])
+		}
+		p, err := f.encode(n)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: length: %w", f.Name, err)
+		}
+		parts[i] = p
+	}
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if _, ok := rec[f.Name]; ok || f.Checksum == "" {
+			continue
+		}
+		var data []byte
+		for _, j := range f.over {
+			data = append(data, parts[j]...)
+		}
+		p, err := f.encode(checksums[f.Checksum].sum(data))
+		if err != nil {
+			return nil, fmt.Errorf("field %q: checksum: %w", f.Name, err)
+		}
+		parts[i] = p
+	}
+	return bytes.Join(parts, nil), nil
+}
+
+// encoder is the part of bitflux.EncLE and bitflux.EncBE used to write fields.
+type encoder interface {
+	U8(v uint8)
+	U16(v uint16)
+	U32(v uint32)
+	U64(v uint64)
+	F32(v float32)
+	F64(v float64)
+	Write(p []byte)
+}
+
+// encode returns the binary form of the value v of f.
+func (f *Field) encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	var e encoder
+	if f.little {
+		e = bitflux.NewEncLE(&buf)
+	} else {
+		e = bitflux.NewEncBE(&buf)
+	}
+	switch f.Type {
+	case "f32":
+		x, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		e.F32(float32(x))
+	case "f64":
+		x, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		e.F64(x)
+	case "bytes", "string":
+		p, err := toBytes(v, f.Type == "string")
+		if err != nil {
+			return nil, err
+		}
+		if f.Size > 0 {
+			if len(p) > f.Size {
+				return nil, fmt.Errorf("%d bytes exceed size %d", len(p), f.Size)
+			}
+			p = append(p, make([]byte, f.Size-len(p))...)
+		}
+		e.Write(p)
+	default:
+		bits := 8 * numberSizes[f.Type]
+		var u uint64
+		if f.Type[0] == 'i' {
+			x, err := toInt(v)
+			if err != nil {
+				return nil, err
+			}
+			if bits < 64 && (x < -1<<(bits-1) || x >= 1<<(bits-1)) {
+				return nil, fmt.Errorf("%d overflows %s", x, f.Type)
+			}
+			u = uint64(x)
+		} else {
+			x, err := toUint(v)
+			if err != nil {
+				return nil, err
+			}
+			if bits < 64 && x >= 1<<bits {
+				return nil, fmt.Errorf("%d overflows %s", x, f.Type)
+			}
+			u = x
+		}
+		switch bits {
+		case 8:
+			e.U8(uint8(u))
+		case 16:
+			e.U16(uint16(u))
+		case 32:
+			e.U32(uint32(u))
+		default:
+			e.U64(u)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func toInt(v any) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint64:
+		if x > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", x)
+		}
+		return int64(x), nil
+	case float64:
+		if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", x)
+		}
+		return int64(x), nil
+	case json.Number:
+		return strconv.ParseInt(string(x), 0, 64)
+	case string:
+		return strconv.ParseInt(x, 0, 64)
+	}
+	return 0, fmt.Errorf("%v is not an integer", v)
+}
+
+func toUint(v any) (uint64, error) {
+	switch x := v.(type) {
+	case uint64:
+		return x, nil
+	case json.Number:
+		return strconv.ParseUint(string(x), 0, 64)
+	case string:
+		return strconv.ParseUint(x, 0, 64)
+	case float64:
+		if x != math.Trunc(x) || x < 0 || x >= math.MaxUint64 {
+			return 0, fmt.Errorf("%v is not an unsigned integer", x)
+		}
+		return uint64(x), nil
+	}
+	n, err := toInt(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%d is negative", n)
+	}
+	return uint64(n), nil
+}
+
+func toFloat(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case int:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case json.Number:
+		return x.Float64()
+	case string:
+		return strconv.ParseFloat(x, 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+// toBytes converts a string or a hex string, with optional spaces and colons between
+// bytes, or a list of byte values to bytes.
+func toBytes(v any, text bool) ([]byte, error) {
+	switch x := v.(type) {
+	case string:
+		if text {
+			return []byte(x), nil
+		}
+		p, err := hex.DecodeString(strings.NewReplacer(" ", "", ":", "").Replace(x))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex %q", x)
+		}
+		return p, nil
+	case []any:
+		if text {
+			break
+		}
+		p := make([]byte, len(x))
+		for i, c := range x {
+			b, err := toUint(c)
+			if err != nil || b > math.MaxUint8 {
+				return nil, fmt.Errorf("element %d: %v is not a byte", i, c)
+			}
+			p[i] = byte(b)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%v is not a string", v)
+}
+
+// Records reads the records in r. The input is a stream of JSON objects or arrays
+// of objects if it starts with '{' or '[', and YAML documents holding a mapping
+// or a sequence of mappings otherwise.
+func Records(r io.Reader) ([]map[string]any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var recs []map[string]any
+	add := func(v any) error {
+		switch x := v.(type) {
+		case nil:
+		case map[string]any:
+			recs = append(recs, x)
+		case []any:
+			for _, e := range x {
+				m, ok := e.(map[string]any)
+				if !ok {
+					return fmt.Errorf("record %d is not an object", len(recs)+1)
+				}
+				recs = append(recs, m)
+			}
+		default:
+			return fmt.Errorf("record %d is not an object", len(recs)+1)
+		}
+		return nil
+	}
+
+	if t := bytes.TrimSpace(data); len(t) > 0 && slices.Contains([]byte("{["), t[0]) {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		for {
+			var v any
+			if err := d.Decode(&v); err == io.EOF {
+				return recs, nil
+			} else if err != nil {
+				return nil, err
+			}
+			if err := add(v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var v any
+		if err := d.Decode(&v); err == io.EOF {
+			return recs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := add(v); err != nil {
+			return nil, err
+		}
+	}
+}
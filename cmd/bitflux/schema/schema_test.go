@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jon-ski/bitflux/modbus"
+)
+
+const testSchema = `
+name: telemetry
+byteOrder: big
+fields:
+  - {name: sync, type: u16, value: 0xEB90}
+  - {name: length, type: u8, lengthOf: [id, payload]}
+  - {name: id, type: u16}
+  - {name: temp, type: i16, byteOrder: little}
+  - {name: payload, type: bytes}
+  - {name: tag, type: string, size: 4, value: ""}
+  - {name: crc, type: u16, byteOrder: little, checksum: crc16-modbus, over: [length, id, payload]}
+`
+
+func TestEncode(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for _, in := range []string{
+		`{"id": 258, "temp": -2, "payload": "aa bb cc", "tag": "ab"}`,
+		"id: 0x102\ntemp: -2\npayload: [0xAA, 0xBB, 0xCC]\ntag: ab\n",
+	} {
+		recs, err := Records(strings.NewReader(in))
+		if err != nil || len(recs) != 1 {
+			t.Fatalf("Records(%q): got=%v err=%v", in, recs, err)
+		}
+		got, err := s.Encode(recs[0])
+		if err != nil {
+			t.Fatalf("Encode error: %v", err)
+		}
+		crc := modbus.CRC16([]byte{0x05, 0x01, 0x02, 0xAA, 0xBB, 0xCC})
+		want := []byte{0xEB, 0x90, 0x05, 0x01, 0x02, 0xFE, 0xFF, 0xAA, 0xBB, 0xCC, 'a', 'b', 0, 0, byte(crc), byte(crc >> 8)}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Encode(%q): got=% x, want=% x", in, got, want)
+		}
+	}
+}
+
+func TestEncodeOverride(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	got, err := s.Encode(map[string]any{"id": 1, "temp": 0, "payload": "", "length": 9, "crc": 0xBEEF})
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	want := []byte{0xEB, 0x90, 0x09, 0x00, 0x01, 0x00, 0x00, 0, 0, 0, 0, 0xEF, 0xBE}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got=% x, want=% x", got, want)
+	}
+}
+
+func TestRecordsStream(t *testing.T) {
+	for _, in := range []string{
+		`{"a": 1} {"a": 2} [{"a": 3}]`,
+		"a: 1\n---\na: 2\n---\n- a: 3\n",
+	} {
+		recs, err := Records(strings.NewReader(in))
+		if err != nil {
+			t.Fatalf("Records(%q) error: %v", in, err)
+		}
+		if len(recs) != 3 {
+			t.Errorf("Records(%q): got %d records, want 3", in, len(recs))
+		}
+	}
+	if _, err := Records(strings.NewReader(`[1]`)); err == nil {
+		t.Errorf("expected an error for a record that is not an object")
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	s, err := Parse([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for _, rec := range []map[string]any{
+		{"temp": 0, "payload": ""},                                 // missing id
+		{"id": 1, "temp": 0, "payload": "", "bogus": 1},            // unknown field
+		{"id": 65536, "temp": 0, "payload": ""},                    // overflow
+		{"id": -1, "temp": 0, "payload": ""},                       // negative unsigned
+		{"id": 1, "temp": 0, "payload": "xyz"},                     // invalid hex
+		{"id": 1, "temp": 0, "payload": "", "tag": "toolong"},      // string over size
+		{"id": 1, "temp": 0, "payload": strings.Repeat("00", 254)}, // length overflows u8
+	} {
+		if _, err := s.Encode(rec); err == nil {
+			t.Errorf("Encode(%v): expected an error", rec)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		`fields: [{name: a, type: u24}]`,
+		`fields: [{name: a, type: u8}, {name: a, type: u8}]`,
+		`fields: [{name: a, type: u8, size: 2}]`,
+		`fields: [{name: a, type: bytes, lengthOf: [a]}]`,
+		`fields: [{name: a, type: u8, lengthOf: [b]}]`,
+		`fields: [{name: a, type: u8, checksum: crc32}]`,
+		`fields: [{name: a, type: u16, checksum: md5}]`,
+		`fields: [{name: a, type: u16, checksum: sum8, over: [a]}]`,
+		`fields: [{name: a, type: u8, checksum: sum8, value: 1}]`,
+		`fields: [{name: a, type: u8, bogus: 1}]`,
+		`byteOrder: middle
+fields: [{name: a, type: u8}]`,
+		`fields: []`,
+	} {
+		if _, err := Parse([]byte(in)); err == nil {
+			t.Errorf("Parse(%q): expected an error", in)
+		}
+	}
+}
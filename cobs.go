@@ -0,0 +1,265 @@
+package bitflux
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrCOBSInvalid is reported when COBS encoded data is malformed.
+var ErrCOBSInvalid = errors.New("bitflux: invalid COBS encoding")
+
+// cobsMaxBlock is the largest number of data bytes in a single COBS block.
+const cobsMaxBlock = 254
+
+// AppendCOBS appends the COBS encoding of src to dst and returns the extended slice.
+// The 0x00 frame delimiter is not appended.
+func AppendCOBS(dst, src []byte) []byte { return appendCOBS(dst, src, false) }
+
+// AppendCOBSR appends the COBS/R (reduced) encoding of src to dst and returns the extended slice.
+// The 0x00 frame delimiter is not appended.
+func AppendCOBSR(dst, src []byte) []byte { return appendCOBS(dst, src, true) }
+
+// DecodeCOBS appends the decoding of the COBS encoded src to dst and returns the extended slice.
+// src must not include the 0x00 frame delimiter.
+func DecodeCOBS(dst, src []byte) ([]byte, error) { return decodeCOBS(dst, src, false) }
+
+// DecodeCOBSR appends the decoding of the COBS/R encoded src to dst and returns the extended slice.
+// src must not include the 0x00 frame delimiter.
+func DecodeCOBSR(dst, src []byte) ([]byte, error) { return decodeCOBS(dst, src, true) }
+
+func appendCOBS(dst, src []byte, reduced bool) []byte {
+	code := len(dst)
+	dst = append(dst, 0)
+	full := false
+	for _, c := range src {
+		full = c != 0
+		if full {
+			dst = append(dst, c)
+			if len(dst)-code <= cobsMaxBlock {
+				continue
+			}
+		}
+		dst[code] = byte(len(dst) - code)
+		code = len(dst)
+		dst = append(dst, 0)
+	}
+	if full && len(dst)-code == 1 {
+		// A frame ending with a full block needs no trailing empty block.
+		return dst[:code]
+	}
+	return finishCOBS(dst, code, reduced)
+}
+
+// finishCOBS writes the code byte of the final block starting at dst[code].
+// For COBS/R the final data byte replaces the code byte when it is larger than the block length.
+func finishCOBS(dst []byte, code int, reduced bool) []byte {
+	n := len(dst) - code
+	if last := dst[len(dst)-1]; reduced && n > 1 && int(last) > n {
+		dst[code] = last
+		return dst[:len(dst)-1]
+	}
+	dst[code] = byte(n)
+	return dst
+}
+
+func decodeCOBS(dst, src []byte, reduced bool) ([]byte, error) {
+	for i := 0; i < len(src); {
+		code := src[i]
+		if code == 0 {
+			return dst, ErrCOBSInvalid
+		}
+		i++
+		end := i + int(code) - 1
+		if end > len(src) {
+			if !reduced {
+				return dst, ErrCOBSInvalid
+			}
+			// COBS/R: the code byte is the last data byte of the frame.
+			if err := cobsCheck(src[i:]); err != nil {
+				return dst, err
+			}
+			dst = append(dst, src[i:]...)
+			return append(dst, code), nil
+		}
+		if err := cobsCheck(src[i:end]); err != nil {
+			return dst, err
+		}
+		dst = append(dst, src[i:end]...)
+		i = end
+		if code != cobsMaxBlock+1 && i < len(src) {
+			dst = append(dst, 0)
+		}
+	}
+	return dst, nil
+}
+
+func cobsCheck(p []byte) error {
+	for _, c := range p {
+		if c == 0 {
+			return ErrCOBSInvalid
+		}
+	}
+	return nil
+}
+
+// COBSWriter encodes a stream of bytes as COBS frames delimited by 0x00.
+// Bytes written are encoded on the fly so an encoder such as EncLE can write
+// directly into it; EndFrame terminates the current frame.
+type COBSWriter struct {
+	W       io.Writer // The underlying writer to write frames to
+	N       int64     // Number of encoded bytes written, including delimiters
+	Err     error     // First error encountered during framing
+	Reduced bool      // Use COBS/R encoding
+	blk     [cobsMaxBlock + 2]byte
+	n       int
+	full    bool
+}
+
+// NewCOBSWriter creates a new COBS frame writer that writes to the provided io.Writer.
+func NewCOBSWriter(w io.Writer) *COBSWriter { return &COBSWriter{W: w, n: 1} }
+
+// NewCOBSRWriter creates a new COBS/R frame writer that writes to the provided io.Writer.
+func NewCOBSRWriter(w io.Writer) *COBSWriter { return &COBSWriter{W: w, n: 1, Reduced: true} }
+
+// push writes the provided byte slice to the underlying writer.
+func (c *COBSWriter) push(p []byte) {
+	if c.Err != nil {
+		return
+	}
+	n, err := c.W.Write(p)
+	c.N += int64(n)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	c.Err = err
+}
+
+// Write encodes p into the current frame. It implements io.Writer.
+func (c *COBSWriter) Write(p []byte) (int, error) {
+	if c.Err != nil {
+		return 0, c.Err
+	}
+	if c.n == 0 {
+		c.n = 1
+	}
+	for _, b := range p {
+		c.full = b != 0
+		if c.full {
+			c.blk[c.n] = b
+			c.n++
+			if c.n <= cobsMaxBlock {
+				continue
+			}
+		}
+		c.blk[0] = byte(c.n)
+		c.push(c.blk[:c.n])
+		c.n = 1
+	}
+	if c.Err != nil {
+		return 0, c.Err
+	}
+	return len(p), nil
+}
+
+// EndFrame encodes the final block of the current frame and writes the 0x00 delimiter.
+func (c *COBSWriter) EndFrame() error {
+	if c.Err != nil {
+		return c.Err
+	}
+	if c.n == 0 {
+		c.n = 1
+	}
+	if c.full && c.n == 1 {
+		c.blk[0] = 0
+		c.push(c.blk[:1])
+	} else {
+		blk := finishCOBS(c.blk[:c.n], 0, c.Reduced)
+		c.push(append(blk, 0))
+	}
+	c.n, c.full = 1, false
+	return c.Err
+}
+
+// WriteFrame encodes p as a complete frame.
+func (c *COBSWriter) WriteFrame(p []byte) error {
+	c.Write(p)
+	return c.EndFrame()
+}
+
+// COBSReader reads 0x00 delimited COBS frames from an io.Reader, one frame at a time.
+// Malformed and oversized frames are discarded and counted in Stats.
+type COBSReader struct {
+	R        io.ByteReader // The underlying reader to read frames from
+	MaxFrame int           // Maximum decoded frame size; frames larger than this are dropped
+	Reduced  bool          // Decode COBS/R instead of COBS
+	Stats    FrameStats    // Frame statistics
+	Err      error         // First error encountered while reading
+	raw      []byte
+	frame    []byte
+}
+
+// NewCOBSReader creates a new COBS frame reader that reads from the provided io.Reader.
+// If r does not implement io.ByteReader it is wrapped in a bufio.Reader.
+func NewCOBSReader(r io.Reader) *COBSReader {
+	return &COBSReader{R: byteReader(r), MaxFrame: DefaultMaxFrame}
+}
+
+// NewCOBSRReader creates a new COBS/R frame reader that reads from the provided io.Reader.
+// If r does not implement io.ByteReader it is wrapped in a bufio.Reader.
+func NewCOBSRReader(r io.Reader) *COBSReader {
+	return &COBSReader{R: byteReader(r), MaxFrame: DefaultMaxFrame, Reduced: true}
+}
+
+// ReadFrame returns the next decoded frame, skipping consecutive delimiters.
+// The returned slice is only valid until the next call to ReadFrame.
+// A partial frame at the end of the stream is counted as dropped and io.ErrUnexpectedEOF is returned.
+func (c *COBSReader) ReadFrame() ([]byte, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	// Encoded frames are at most one code byte per block larger than the decoded frame.
+	limit := -1
+	if c.MaxFrame > 0 {
+		limit = c.MaxFrame + c.MaxFrame/cobsMaxBlock + 1
+	}
+	c.raw = c.raw[:0]
+	over := false
+	for {
+		b, err := c.R.ReadByte()
+		if err != nil {
+			if len(c.raw) > 0 || over {
+				c.Stats.Dropped++
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			c.Err = err
+			return nil, err
+		}
+		if b != 0 {
+			if limit >= 0 && len(c.raw) >= limit {
+				over = true
+			} else if !over {
+				c.raw = append(c.raw, b)
+			}
+			continue
+		}
+		if over {
+			c.Stats.Oversized++
+		} else if len(c.raw) > 0 {
+			frame, err := decodeCOBS(c.frame[:0], c.raw, c.Reduced)
+			c.frame = frame
+			switch {
+			case err != nil:
+				c.Stats.Dropped++
+			case c.MaxFrame > 0 && len(frame) > c.MaxFrame:
+				c.Stats.Oversized++
+			default:
+				c.Stats.Frames++
+				return frame, nil
+			}
+		}
+		c.raw = c.raw[:0]
+		over = false
+	}
+}
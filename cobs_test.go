@@ -0,0 +1,195 @@
+package bitflux
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func seq(from, to int) []byte {
+	var b []byte
+	for i := from; i <= to; i++ {
+		b = append(b, byte(i))
+	}
+	return b
+}
+
+func cat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestCOBSVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"empty", []byte{}, []byte{0x01}},
+		{"zero", []byte{0x00}, []byte{0x01, 0x01}},
+		{"two zeros", []byte{0x00, 0x00}, []byte{0x01, 0x01, 0x01}},
+		{"zero data zero", []byte{0x00, 0x11, 0x00}, []byte{0x01, 0x02, 0x11, 0x01}},
+		{"mixed", []byte{0x11, 0x22, 0x00, 0x33}, []byte{0x03, 0x11, 0x22, 0x02, 0x33}},
+		{"no zeros", []byte{0x11, 0x22, 0x33, 0x44}, []byte{0x05, 0x11, 0x22, 0x33, 0x44}},
+		{"trailing zeros", []byte{0x11, 0x00, 0x00, 0x00}, []byte{0x02, 0x11, 0x01, 0x01, 0x01}},
+		{"254 bytes", seq(0x01, 0xFE), cat([]byte{0xFF}, seq(0x01, 0xFE))},
+		{"leading zero 254", seq(0x00, 0xFE), cat([]byte{0x01, 0xFF}, seq(0x01, 0xFE))},
+		{"255 bytes", seq(0x01, 0xFF), cat([]byte{0xFF}, seq(0x01, 0xFE), []byte{0x02, 0xFF})},
+		{"full block then zero", cat(seq(0x02, 0xFF), []byte{0x00}), cat([]byte{0xFF}, seq(0x02, 0xFF), []byte{0x01, 0x01})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AppendCOBS(nil, tt.in)
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("AppendCOBS: got=% x, want=% x", got, tt.want)
+			}
+			dec, err := DecodeCOBS(nil, got)
+			if err != nil {
+				t.Fatalf("DecodeCOBS error: %v", err)
+			}
+			if !bytes.Equal(dec, tt.in) {
+				t.Fatalf("DecodeCOBS: got=% x, want=% x", dec, tt.in)
+			}
+		})
+	}
+}
+
+func TestCOBSRVectors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"empty", []byte{}, []byte{0x01}},
+		{"small last", []byte{0x01}, []byte{0x02, 0x01}},
+		{"reduced", []byte{0x05}, []byte{0x05}},
+		{"reduced block", []byte{0x11, 0x22, 0x00, 0x33}, []byte{0x03, 0x11, 0x22, 0x33}},
+		{"not reduced", []byte{0x11, 0x00, 0x02}, []byte{0x02, 0x11, 0x02, 0x02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AppendCOBSR(nil, tt.in)
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("AppendCOBSR: got=% x, want=% x", got, tt.want)
+			}
+			dec, err := DecodeCOBSR(nil, got)
+			if err != nil {
+				t.Fatalf("DecodeCOBSR error: %v", err)
+			}
+			if !bytes.Equal(dec, tt.in) {
+				t.Fatalf("DecodeCOBSR: got=% x, want=% x", dec, tt.in)
+			}
+		})
+	}
+}
+
+func TestCOBSDecodeInvalid(t *testing.T) {
+	for _, in := range [][]byte{{0x00}, {0x05, 0x11}, {0x03, 0x11, 0x00}} {
+		if _, err := DecodeCOBS(nil, in); err != ErrCOBSInvalid {
+			t.Errorf("DecodeCOBS(% x): expected ErrCOBSInvalid, got %v", in, err)
+		}
+	}
+}
+
+func TestCOBSWriterMatchesAppend(t *testing.T) {
+	payloads := [][]byte{
+		{},
+		{0x00},
+		{0x11, 0x22, 0x00, 0x33},
+		seq(0x01, 0xFE),
+		seq(0x00, 0xFF),
+		cat(seq(0x01, 0xFF), seq(0x00, 0xFF), seq(0x01, 0xFF)),
+	}
+	for _, reduced := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewCOBSWriter(&buf)
+		w.Reduced = reduced
+		var want []byte
+		for _, p := range payloads {
+			// Write in small pieces to exercise block handling across calls.
+			for i := 0; i < len(p); i += 7 {
+				w.Write(p[i:min(i+7, len(p))])
+			}
+			w.EndFrame()
+			want = append(appendCOBS(want, p, reduced), 0x00)
+		}
+		if w.Err != nil {
+			t.Fatalf("unexpected error: %v", w.Err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Fatalf("reduced=%v: got=% x, want=% x", reduced, buf.Bytes(), want)
+		}
+
+		r := NewCOBSReader(&buf)
+		r.Reduced = reduced
+		for i, p := range payloads {
+			got, err := r.ReadFrame()
+			if err != nil {
+				t.Fatalf("reduced=%v frame %d: unexpected error: %v", reduced, i, err)
+			}
+			if !bytes.Equal(got, p) {
+				t.Fatalf("reduced=%v frame %d: got=% x, want=% x", reduced, i, got, p)
+			}
+		}
+		if _, err := r.ReadFrame(); err != io.EOF {
+			t.Fatalf("expected io.EOF, got %v", err)
+		}
+	}
+}
+
+func TestCOBSWithEncoders(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCOBSWriter(&buf)
+	enc := NewEncLE(w)
+	enc.U16(0x0100)
+	enc.U32(0xDEADBEEF)
+	w.EndFrame()
+	if enc.Err != nil || w.Err != nil {
+		t.Fatalf("unexpected error: enc=%v, cobs=%v", enc.Err, w.Err)
+	}
+
+	r := NewCOBSReader(&buf)
+	frame, err := r.ReadFrame()
+	if err != nil {
+		t.Fatalf("ReadFrame error: %v", err)
+	}
+	dec := NewDecLE(bytes.NewReader(frame))
+	if v := dec.U16(); v != 0x0100 {
+		t.Errorf("U16: got=%#x, want=0x100", v)
+	}
+	if v := dec.U32(); v != 0xDEADBEEF {
+		t.Errorf("U32: got=%#x, want=0xdeadbeef", v)
+	}
+	if dec.Err != nil {
+		t.Fatalf("unexpected decode error: %v", dec.Err)
+	}
+}
+
+func TestCOBSReaderDropsBadFrames(t *testing.T) {
+	stream := []byte{
+		0x05, 0x11, 0x00, // invalid
+		0x06, 0x01, 0x02, 0x03, 0x04, 0x05, 0x00, // oversized
+		0x03, 0x0A, 0x0B, 0x00, // good
+		0x02, // truncated
+	}
+	r := NewCOBSReader(bytes.NewReader(stream))
+	r.MaxFrame = 4
+
+	got, err := r.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, []byte{0x0A, 0x0B}) {
+		t.Fatalf("got=% x, want=0a 0b", got)
+	}
+	if _, err := r.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	want := FrameStats{Frames: 1, Dropped: 2, Oversized: 1}
+	if r.Stats != want {
+		t.Errorf("Stats: got=%+v, want=%+v", r.Stats, want)
+	}
+}
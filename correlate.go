@@ -0,0 +1,224 @@
+package bitflux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownID is reported for a response whose transaction ID has no waiting request.
+	ErrUnknownID = errors.New("bitflux: response for unknown transaction ID")
+	// ErrIDsInUse is returned by Call when every transaction ID is waiting for a response.
+	ErrIDsInUse = errors.New("bitflux: all transaction IDs in use")
+	// ErrCorrelatorClosed is returned by calls made or pending when the correlator is closed.
+	ErrCorrelatorClosed = errors.New("bitflux: correlator closed")
+)
+
+// Correlator sends requests tagged with a transaction ID over an io.ReadWriter and
+// matches the responses back to the waiting callers, so several requests can be
+// outstanding at once, as in Modbus TCP.
+//
+// Responses are read by a goroutine started with the first call. If reading fails,
+// or a call gives up on a partly written request, the pending and later calls fail
+// with the error.
+type Correlator struct {
+	RW      io.ReadWriter
+	Timeout time.Duration // Time limit for each call in addition to its context; 0 means none
+	MaxID   uint64        // Largest transaction ID, after which IDs wrap to 0; 0 means 0xFFFF
+
+	// Warn, if set, is called with an error wrapping ErrUnknownID for responses that
+	// match no pending request, and the response is dropped. Otherwise such a response
+	// fails all calls. Late responses to timed out or canceled calls are always dropped,
+	// and reported to Warn if set.
+	Warn func(err error)
+
+	read  func(r io.Reader) (id uint64, resp any, err error)
+	start sync.Once
+	wsem  chan struct{} // held while writing a request
+	buf   []byte        // request buffer, guarded by wsem
+
+	mu      sync.Mutex
+	next    uint64
+	pending map[uint64]chan correlated
+	expired map[uint64]bool // IDs of calls that gave up before their response
+	err     error
+}
+
+// correlated is a response delivered to a waiting call.
+type correlated struct {
+	resp any
+	err  error
+}
+
+// NewCorrelator creates a correlator that writes requests to rw and reads responses
+// with read, which must consume exactly one response from r and return its transaction ID.
+func NewCorrelator(rw io.ReadWriter, read func(r io.Reader) (id uint64, resp any, err error)) *Correlator {
+	return &Correlator{
+		RW:      rw,
+		read:    read,
+		wsem:    make(chan struct{}, 1),
+		pending: make(map[uint64]chan correlated),
+		expired: make(map[uint64]bool),
+	}
+}
+
+// Call assigns the next free transaction ID, appends the request for it to a buffer
+// with encode and writes the buffer in one call. It then waits for the response with
+// the same ID. If ctx is done or Timeout passes first, including while writing, the
+// call returns ctx.Err() and a late response is dropped.
+func (c *Correlator) Call(ctx context.Context, encode func(b []byte, id uint64) []byte) (any, error) {
+	c.start.Do(func() { go c.readLoop() })
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	ch := make(chan correlated, 1)
+	id, err := c.register(ch)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.write(ctx, encode, id); err != nil {
+		c.unregister(id, false)
+		return nil, err
+	}
+	select {
+	case r := <-ch:
+		return r.resp, r.err
+	case <-ctx.Done():
+		c.unregister(id, true)
+		return nil, ctx.Err()
+	}
+}
+
+// write writes the request for id unless ctx is done first. A write still in
+// progress when ctx is done may have sent part of the request, so it fails the
+// correlator; closing it unblocks the write.
+func (c *Correlator) write(ctx context.Context, encode func(b []byte, id uint64) []byte, id uint64) error {
+	select {
+	case c.wsem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := ctx.Err(); err != nil {
+		<-c.wsem
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-c.wsem }()
+		c.buf = encode(c.buf[:0], id)
+		_, err := writeAll(c.RW, c.buf)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		c.fail(fmt.Errorf("bitflux: request %d not written: %w", id, ctx.Err()))
+		return ctx.Err()
+	}
+}
+
+// Close fails the pending calls with ErrCorrelatorClosed and closes RW if it is an io.Closer,
+// which also ends the reading goroutine.
+func (c *Correlator) Close() error {
+	c.fail(ErrCorrelatorClosed)
+	if cl, ok := c.RW.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// register assigns a free transaction ID to ch.
+func (c *Correlator) register(ch chan correlated) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	last := c.MaxID
+	if last == 0 {
+		last = 0xFFFF
+	}
+	if last != ^uint64(0) && uint64(len(c.pending)) > last {
+		return 0, ErrIDsInUse
+	}
+	if last != ^uint64(0) && uint64(len(c.pending)+len(c.expired)) > last {
+		// Every free ID awaits a late response; reuse them rather than fail.
+		clear(c.expired)
+	}
+	for {
+		if c.next >= last {
+			c.next = 0
+		} else {
+			c.next++
+		}
+		if _, busy := c.pending[c.next]; !busy && !c.expired[c.next] {
+			c.pending[c.next] = ch
+			return c.next, nil
+		}
+	}
+}
+
+// unregister stops waiting for id. If expire is set and the response has not
+// arrived yet, the ID is kept back until it does so the response is dropped.
+func (c *Correlator) unregister(id uint64, expire bool) {
+	c.mu.Lock()
+	if _, ok := c.pending[id]; ok && expire {
+		c.expired[id] = true
+	}
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// fail makes the pending and later calls fail with err.
+func (c *Correlator) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	for id, ch := range c.pending {
+		ch <- correlated{err: err}
+		delete(c.pending, id)
+	}
+}
+
+// readLoop delivers responses to the pending calls until reading fails.
+func (c *Correlator) readLoop() {
+	for {
+		id, resp, err := c.read(c.RW)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			c.fail(err)
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		late := c.expired[id]
+		delete(c.pending, id)
+		delete(c.expired, id)
+		c.mu.Unlock()
+		if !ok {
+			err := fmt.Errorf("%w: %d", ErrUnknownID, id)
+			switch {
+			case c.Warn != nil:
+				c.Warn(err)
+			case !late:
+				c.fail(err)
+				return
+			}
+			continue
+		}
+		ch <- correlated{resp: resp}
+	}
+}
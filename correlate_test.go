@@ -0,0 +1,214 @@
+package bitflux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test protocol: requests and responses are a big-endian u16 transaction ID and a u16 value.
+func readCorrelated(r io.Reader) (uint64, any, error) {
+	dec := NewDecBE(r)
+	id, v := dec.U16(), dec.U16()
+	return uint64(id), v, dec.Err
+}
+
+func encodeCorrelated(v uint16) func(b []byte, id uint64) []byte {
+	return func(b []byte, id uint64) []byte {
+		return AppendU16BE(AppendU16BE(b, uint16(id)), v)
+	}
+}
+
+// doublingServer answers requests on conn with twice their value, collecting batch
+// requests and answering them in reverse order. Requests with value 0 are not answered.
+func doublingServer(conn net.Conn, batch int) {
+	dec := NewDecBE(conn)
+	enc := NewEncBE(conn)
+	for {
+		var ids, vals []uint16
+		for len(ids) < batch {
+			id, v := dec.U16(), dec.U16()
+			if dec.Err != nil {
+				return
+			}
+			if v != 0 {
+				ids, vals = append(ids, id), append(vals, v)
+			}
+		}
+		for i := len(ids) - 1; i >= 0; i-- {
+			enc.U16(ids[i])
+			enc.U16(2 * vals[i])
+		}
+	}
+}
+
+func TestCorrelatorConcurrent(t *testing.T) {
+	client, server := net.Pipe()
+	go doublingServer(server, 4)
+	c := NewCorrelator(client, readCorrelated)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(v uint16) {
+			defer wg.Done()
+			resp, err := c.Call(context.Background(), encodeCorrelated(v))
+			if err != nil || resp != 2*v {
+				t.Errorf("Call(%d): got=%v err=%v", v, resp, err)
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+}
+
+func TestCorrelatorTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	go doublingServer(server, 1)
+	c := NewCorrelator(client, readCorrelated)
+	defer c.Close()
+	warned := make(chan error, 1)
+	c.Warn = func(err error) { warned <- err }
+	c.Timeout = 20 * time.Millisecond
+
+	if _, err := c.Call(context.Background(), encodeCorrelated(0)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got err=%v, want DeadlineExceeded", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Call(ctx, encodeCorrelated(0)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got err=%v, want Canceled", err)
+	}
+	if resp, err := c.Call(context.Background(), encodeCorrelated(21)); err != nil || resp != uint16(42) {
+		t.Fatalf("got=%v err=%v", resp, err)
+	}
+
+	// A response nobody waits for is reported and dropped.
+	server.Write([]byte{0x12, 0x34, 0x00, 0x00})
+	select {
+	case err := <-warned:
+		if !errors.Is(err, ErrUnknownID) {
+			t.Fatalf("got err=%v, want ErrUnknownID", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no warning for unknown ID")
+	}
+}
+
+func TestCorrelatorLateResponse(t *testing.T) {
+	client, server := net.Pipe()
+	c := NewCorrelator(client, readCorrelated)
+	defer c.Close()
+	go func() {
+		req := make([]byte, 4)
+		io.ReadFull(server, req)
+		time.Sleep(50 * time.Millisecond)
+		server.Write(req) // late answer to the timed out call
+		io.ReadFull(server, req)
+		server.Write(req)
+	}()
+
+	c.Timeout = 10 * time.Millisecond
+	if _, err := c.Call(context.Background(), encodeCorrelated(1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got err=%v, want DeadlineExceeded", err)
+	}
+	c.Timeout = 0
+	if resp, err := c.Call(context.Background(), encodeCorrelated(2)); err != nil || resp != uint16(2) {
+		t.Fatalf("call after timeout: got=%v err=%v", resp, err)
+	}
+}
+
+func TestCorrelatorWriteTimeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	c := NewCorrelator(client, readCorrelated)
+	defer c.Close()
+	c.Timeout = 10 * time.Millisecond
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Call(context.Background(), encodeCorrelated(1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got err=%v, want DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked write did not time out")
+	}
+	if _, err := c.Call(context.Background(), encodeCorrelated(1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("later call: got err=%v, want the write error", err)
+	}
+}
+
+func TestCorrelatorUnknownIDFails(t *testing.T) {
+	client, server := net.Pipe()
+	c := NewCorrelator(client, readCorrelated)
+	defer c.Close()
+	go func() {
+		io.ReadFull(server, make([]byte, 4))
+		server.Write([]byte{0xFF, 0xFF, 0x00, 0x00})
+	}()
+	if _, err := c.Call(context.Background(), encodeCorrelated(1)); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("got err=%v, want ErrUnknownID", err)
+	}
+	if _, err := c.Call(context.Background(), encodeCorrelated(1)); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("later call: got err=%v, want ErrUnknownID", err)
+	}
+}
+
+func TestCorrelatorClose(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(io.Discard, server)
+	c := NewCorrelator(client, readCorrelated)
+	done := make(chan error)
+	go func() {
+		_, err := c.Call(context.Background(), encodeCorrelated(1))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	c.Close()
+	if err := <-done; !errors.Is(err, ErrCorrelatorClosed) {
+		t.Fatalf("got err=%v, want ErrCorrelatorClosed", err)
+	}
+}
+
+func TestCorrelatorIDs(t *testing.T) {
+	c := NewCorrelator(nil, nil)
+	c.MaxID = 2
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		id, err := c.register(make(chan correlated, 1))
+		if err != nil {
+			t.Fatalf("register: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if ids[0] != 1 || ids[1] != 2 || ids[2] != 0 {
+		t.Fatalf("ids: got=%v", ids)
+	}
+	if _, err := c.register(make(chan correlated, 1)); !errors.Is(err, ErrIDsInUse) {
+		t.Fatalf("got err=%v, want ErrIDsInUse", err)
+	}
+	c.unregister(1, false)
+	if id, _ := c.register(make(chan correlated, 1)); id != 1 {
+		t.Fatalf("reused id: got=%d, want=1", id)
+	}
+
+	// An ID awaiting a late response is skipped until no other ID is free.
+	c.unregister(2, true)
+	c.unregister(1, false)
+	if id, _ := c.register(make(chan correlated, 1)); id != 1 {
+		t.Fatalf("skipped expired id: got=%d, want=1", id)
+	}
+	if id, _ := c.register(make(chan correlated, 1)); id != 2 {
+		t.Fatalf("reclaimed expired id: got=%d, want=2", id)
+	}
+}
@@ -12,49 +12,130 @@ type DecLE struct {
 	R   io.Reader // The underlying reader to decode data from
 	N   int64     // Number of bytes read
 	Err error     // First error encountered during decoding
+
+	Base    int64 // Offset that Align and PadTo are relative to, see MarkBase
+	ZeroPad bool  // Verify that bytes skipped by Align and PadTo are zero
+	MaxMark int   // Limit on bytes buffered between Mark and Commit; 0 means DefaultMaxMark, negative means no limit
+
+	scratch [8]byte
+	bulk    []byte
+	lookahead
 }
 
 // NewDecLE creates a new little-endian decoder that reads from the provided io.Reader.
 func NewDecLE(r io.Reader) *DecLE { return &DecLE{R: r} }
 
-// pull reads the provided byte slice from the underlying reader using io.ReadFull.
+// Reset makes the decoder read from r and clears N, Err, Base, peeked bytes and the mark, so it can be reused.
+// ZeroPad, MaxMark and internal buffers are kept.
+func (d *DecLE) Reset(r io.Reader) {
+	d.R = r
+	d.N = 0
+	d.Err = nil
+	d.Base = 0
+	d.reset()
+}
+
+// bulkBuf returns a reusable buffer for bulk reads and skipping.
+func (d *DecLE) bulkBuf() []byte {
+	if d.bulk == nil {
+		d.bulk = make([]byte, bulkChunk)
+	}
+	return d.bulk
+}
+
+// pull fills the provided byte slice from peeked bytes, then from the underlying reader using io.ReadFull.
 // It tracks the number of bytes read and any errors that occur.
 func (d *DecLE) pull(p []byte) {
 	if d.Err != nil {
 		return
 	}
-	n, err := io.ReadFull(d.R, p)
+	if !d.room(len(p)) {
+		d.Err = ErrMarkLimit
+		return
+	}
+	k := d.take(p)
+	d.N += int64(k)
+	if k == len(p) {
+		d.keep(p)
+		return
+	}
+	n, err := io.ReadFull(d.R, p[k:])
 	d.N += int64(n)
+	d.keep(p[:k+n])
+	if err == io.EOF && k > 0 {
+		err = io.ErrUnexpectedEOF
+	}
 	if err != nil {
 		d.Err = err
 	}
 }
 
+// Peek returns the next n bytes without consuming them, so they are read again
+// by the following calls. The slice is only valid until the next call on d.
+// It returns nil and sets Err if fewer than n bytes are available.
+func (d *DecLE) Peek(n int) []byte {
+	if d.Err != nil || n <= 0 {
+		return nil
+	}
+	if err := d.fill(d.R, n); err != nil {
+		d.Err = err
+		return nil
+	}
+	return d.ahead[:n]
+}
+
+// PeekU8 returns the next uint8 value without consuming it.
+func (d *DecLE) PeekU8() uint8 {
+	b := d.Peek(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+// PeekU16 returns the next uint16 value in little-endian format without consuming it.
+func (d *DecLE) PeekU16() uint16 {
+	b := d.Peek(2)
+	if b == nil {
+		return 0
+	}
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+// PeekU32 returns the next uint32 value in little-endian format without consuming it.
+func (d *DecLE) PeekU32() uint32 {
+	b := d.Peek(4)
+	if b == nil {
+		return 0
+	}
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
 // U8 decodes a uint8 value from little-endian format.
 func (d *DecLE) U8() uint8 {
-	var b [1]byte
-	d.pull(b[:])
+	b := d.scratch[:1]
+	d.pull(b)
 	return b[0]
 }
 
 // U16 decodes a uint16 value from little-endian format.
 func (d *DecLE) U16() uint16 {
-	var b [2]byte
-	d.pull(b[:])
+	b := d.scratch[:2]
+	d.pull(b)
 	return uint16(b[0]) | uint16(b[1])<<8
 }
 
 // U32 decodes a uint32 value from little-endian format.
 func (d *DecLE) U32() uint32 {
-	var b [4]byte
-	d.pull(b[:])
+	b := d.scratch[:4]
+	d.pull(b)
 	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
 }
 
 // U64 decodes a uint64 value from little-endian format.
 func (d *DecLE) U64() uint64 {
-	var b [8]byte
-	d.pull(b[:])
+	b := d.scratch[:8]
+	d.pull(b)
 	return uint64(b[0]) |
 		uint64(b[1])<<8 |
 		uint64(b[2])<<16 |
@@ -106,7 +187,7 @@ func (d *DecLE) Skip(n int) {
 	if n <= 0 || d.Err != nil {
 		return
 	}
-	var scratch [64]byte
+	scratch := d.bulkBuf()
 	for n > 0 && d.Err == nil {
 		k := min(n, len(scratch))
 		d.pull(scratch[:k])
@@ -119,7 +200,7 @@ func (d *DecLE) FromRF(r io.ReaderFrom) {
 	if d.Err != nil {
 		return
 	}
-	n, err := r.ReadFrom(d.R)
+	n, err := r.ReadFrom(d.reader(d.R))
 	d.N += n
 	if err != nil {
 		d.Err = err
@@ -147,10 +228,265 @@ func (d *DecLE) ReadAll() []byte {
 	if d.Err != nil {
 		return nil
 	}
-	data, err := io.ReadAll(d.R)
+	data, err := io.ReadAll(d.reader(d.R))
 	d.N += int64(len(data))
 	if err != nil && err != io.EOF {
 		d.Err = err
 	}
 	return data
 }
+
+// MarkBase makes the current position the base offset for Align and PadTo.
+func (d *DecLE) MarkBase() { d.Base = d.N }
+
+// Align skips pad bytes until the position relative to Base is a multiple of n.
+func (d *DecLE) Align(n int) {
+	d.pad(alignPad(d.N-d.Base, n))
+}
+
+// PadTo skips pad bytes until the position relative to Base reaches offset.
+// It sets Err to ErrPadOverrun if the position is already past offset.
+func (d *DecLE) PadTo(offset int64) {
+	if d.Err != nil {
+		return
+	}
+	n := offset - (d.N - d.Base)
+	if n < 0 {
+		d.Err = ErrPadOverrun
+		return
+	}
+	d.pad(n)
+}
+
+// pad skips n bytes, verifying they are zero when ZeroPad is set.
+func (d *DecLE) pad(n int64) {
+	scratch := d.bulkBuf()
+	for n > 0 && d.Err == nil {
+		k := min(n, int64(len(scratch)))
+		d.pull(scratch[:k])
+		n -= k
+		if d.ZeroPad && d.Err == nil {
+			for _, c := range scratch[:k] {
+				if c != 0 {
+					d.Err = ErrBadPadding
+					return
+				}
+			}
+		}
+	}
+}
+
+// U16s decodes len(v) uint16 values from little-endian format into v.
+func (d *DecLE) U16s(v []uint16) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/2)
+		d.pull(b[:2*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[2*i : 2*i+2]
+			v[i] = uint16(p[0]) | uint16(p[1])<<8
+		}
+		v = v[k:]
+	}
+}
+
+// U32s decodes len(v) uint32 values from little-endian format into v.
+func (d *DecLE) U32s(v []uint32) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/4)
+		d.pull(b[:4*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[4*i : 4*i+4]
+			v[i] = uint32(p[0]) | uint32(p[1])<<8 | uint32(p[2])<<16 | uint32(p[3])<<24
+		}
+		v = v[k:]
+	}
+}
+
+// U64s decodes len(v) uint64 values from little-endian format into v.
+func (d *DecLE) U64s(v []uint64) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/8)
+		d.pull(b[:8*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[8*i : 8*i+8]
+			v[i] = uint64(p[0]) |
+				uint64(p[1])<<8 |
+				uint64(p[2])<<16 |
+				uint64(p[3])<<24 |
+				uint64(p[4])<<32 |
+				uint64(p[5])<<40 |
+				uint64(p[6])<<48 |
+				uint64(p[7])<<56
+		}
+		v = v[k:]
+	}
+}
+
+// I16s decodes len(v) int16 values from little-endian format into v.
+func (d *DecLE) I16s(v []int16) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/2)
+		d.pull(b[:2*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[2*i : 2*i+2]
+			v[i] = int16(uint16(p[0]) | uint16(p[1])<<8)
+		}
+		v = v[k:]
+	}
+}
+
+// I32s decodes len(v) int32 values from little-endian format into v.
+func (d *DecLE) I32s(v []int32) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/4)
+		d.pull(b[:4*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[4*i : 4*i+4]
+			v[i] = int32(uint32(p[0]) | uint32(p[1])<<8 | uint32(p[2])<<16 | uint32(p[3])<<24)
+		}
+		v = v[k:]
+	}
+}
+
+// I64s decodes len(v) int64 values from little-endian format into v.
+func (d *DecLE) I64s(v []int64) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/8)
+		d.pull(b[:8*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[8*i : 8*i+8]
+			v[i] = int64(
+				uint64(p[0]) |
+					uint64(p[1])<<8 |
+					uint64(p[2])<<16 |
+					uint64(p[3])<<24 |
+					uint64(p[4])<<32 |
+					uint64(p[5])<<40 |
+					uint64(p[6])<<48 |
+					uint64(p[7])<<56,
+			)
+		}
+		v = v[k:]
+	}
+}
+
+// F32s decodes len(v) float32 values from little-endian format into v.
+func (d *DecLE) F32s(v []float32) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/4)
+		d.pull(b[:4*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[4*i : 4*i+4]
+			v[i] = math.Float32frombits(uint32(p[0]) | uint32(p[1])<<8 | uint32(p[2])<<16 | uint32(p[3])<<24)
+		}
+		v = v[k:]
+	}
+}
+
+// F64s decodes len(v) float64 values from little-endian format into v.
+func (d *DecLE) F64s(v []float64) {
+	if hostLE {
+		d.pull(sliceBytes(v))
+		return
+	}
+	b := d.bulkBuf()
+	for len(v) > 0 && d.Err == nil {
+		k := min(len(v), len(b)/8)
+		d.pull(b[:8*k])
+		if d.Err != nil {
+			return
+		}
+		for i := range v[:k] {
+			p := b[8*i : 8*i+8]
+			v[i] = math.Float64frombits(
+				uint64(p[0]) |
+					uint64(p[1])<<8 |
+					uint64(p[2])<<16 |
+					uint64(p[3])<<24 |
+					uint64(p[4])<<32 |
+					uint64(p[5])<<40 |
+					uint64(p[6])<<48 |
+					uint64(p[7])<<56,
+			)
+		}
+		v = v[k:]
+	}
+}
+
+// Mark records the current position so that Rewind can return to it.
+// Seekable readers are rewound by seeking; otherwise the bytes read after
+// the mark are buffered, up to MaxMark bytes, until Commit.
+// A new Mark replaces the previous one.
+func (d *DecLE) Mark() {
+	d.mark(d.R, d.N, d.Base, maxMark(d.MaxMark))
+}
+
+// Rewind returns to the position recorded by Mark and clears Err, so another
+// decoding can be attempted. The mark stays active until Commit.
+// It sets Err to ErrNoMark if there is no mark, or to the error of a failed seek.
+func (d *DecLE) Rewind() {
+	if err := d.rewind(d.R); err != nil {
+		d.Err = err
+		return
+	}
+	d.N, d.Base, d.Err = d.markN, d.markBase, nil
+}
+
+// Commit ends the mark and releases the bytes buffered for it.
+func (d *DecLE) Commit() { d.commit() }
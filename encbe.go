@@ -13,11 +13,28 @@ type EncBE struct {
 	W   io.Writer // The underlying writer to encode data to
 	N   int64     // Number of bytes written
 	Err error     // First error encountered during encoding
+
+	Base int64 // Offset that Align and PadTo are relative to, see MarkBase
+	Fill byte  // Value of the pad bytes written by Align and PadTo
+
+	scratch [8]byte
+	bulk    []byte
+	transaction
 }
 
 // NewEncBE creates a new big-endian encoder that writes to the provided io.Writer.
 func NewEncBE(w io.Writer) *EncBE { return &EncBE{W: w} }
 
+// Reset makes the encoder write to w and clears N, Err, Base and open transactions, so it can be reused.
+// Fill and internal buffers are kept.
+func (e *EncBE) Reset(w io.Writer) {
+	e.W = w
+	e.N = 0
+	e.Err = nil
+	e.Base = 0
+	e.reset()
+}
+
 // Convenience constructors for common use cases
 
 // NewEncBEFromBytes creates an encoder that writes to a buffer initialized with existing data
@@ -35,12 +52,17 @@ func NewEncBEWithCapacity(cap int) *EncBE {
 	return NewEncBE(bytes.NewBuffer(make([]byte, 0, cap)))
 }
 
-// push writes the provided byte slice to the underlying writer.
+// push writes the provided byte slice to the underlying writer, or holds it back while a transaction is open.
 // It handles partial writes and tracks the number of bytes written and any errors.
 func (e *EncBE) push(p []byte) {
 	if e.Err != nil || len(p) == 0 {
 		return
 	}
+	if len(e.txs) > 0 {
+		e.pending = append(e.pending, p...)
+		e.N += int64(len(p))
+		return
+	}
 	for off := 0; off < len(p); {
 		n, err := e.W.Write(p[off:])
 		e.N += int64(n)
@@ -56,34 +78,42 @@ func (e *EncBE) push(p []byte) {
 	}
 }
 
+// bulkBuf returns a reusable buffer for bulk and padding writes.
+func (e *EncBE) bulkBuf() []byte {
+	if e.bulk == nil {
+		e.bulk = make([]byte, bulkChunk)
+	}
+	return e.bulk
+}
+
 // U8 encodes a uint8 value in big-endian format.
 func (e *EncBE) U8(v uint8) {
-	var b [1]byte
+	b := e.scratch[:1]
 	b[0] = byte(v)
-	e.push(b[:])
+	e.push(b)
 }
 
 // U16 encodes a uint16 value in big-endian format.
 func (e *EncBE) U16(v uint16) {
-	var b [2]byte
+	b := e.scratch[:2]
 	b[1] = byte(v)
 	b[0] = byte(v >> 8)
-	e.push(b[:])
+	e.push(b)
 }
 
 // U32 encodes a uint32 value in big-endian format.
 func (e *EncBE) U32(v uint32) {
-	var b [4]byte
+	b := e.scratch[:4]
 	b[3] = byte(v)
 	b[2] = byte(v >> 8)
 	b[1] = byte(v >> 16)
 	b[0] = byte(v >> 24)
-	e.push(b[:])
+	e.push(b)
 }
 
 // U64 encodes a uint64 value in big-endian format.
 func (e *EncBE) U64(v uint64) {
-	var b [8]byte
+	b := e.scratch[:8]
 	b[7] = byte(v)
 	b[6] = byte(v >> 8)
 	b[5] = byte(v >> 16)
@@ -92,7 +122,7 @@ func (e *EncBE) U64(v uint64) {
 	b[2] = byte(v >> 40)
 	b[1] = byte(v >> 48)
 	b[0] = byte(v >> 56)
-	e.push(b[:])
+	e.push(b)
 }
 
 // I8 encodes an int8 value in big-endian format.
@@ -135,7 +165,7 @@ func (e *EncBE) To(w io.WriterTo) {
 	if e.Err != nil {
 		return
 	}
-	n, err := w.WriteTo(e.W)
+	n, err := w.WriteTo(e.writer(e.W))
 	e.N += n
 	if err != nil {
 		e.Err = err
@@ -155,3 +185,264 @@ func (e *EncBE) Marshal(m encoding.BinaryMarshaler) {
 	}
 	e.push(buf)
 }
+
+// MarkBase makes the current position the base offset for Align and PadTo.
+func (e *EncBE) MarkBase() { e.Base = e.N }
+
+// Align writes pad bytes until the position relative to Base is a multiple of n.
+func (e *EncBE) Align(n int) {
+	e.pad(alignPad(e.N-e.Base, n))
+}
+
+// PadTo writes pad bytes until the position relative to Base reaches offset.
+// It sets Err to ErrPadOverrun if the position is already past offset.
+func (e *EncBE) PadTo(offset int64) {
+	if e.Err != nil {
+		return
+	}
+	n := offset - (e.N - e.Base)
+	if n < 0 {
+		e.Err = ErrPadOverrun
+		return
+	}
+	e.pad(n)
+}
+
+// pad writes n Fill bytes to the encoder.
+func (e *EncBE) pad(n int64) {
+	b := e.bulkBuf()
+	for n > 0 && e.Err == nil {
+		k := min(n, int64(len(b)))
+		for i := range b[:k] {
+			b[i] = e.Fill
+		}
+		e.push(b[:k])
+		n -= k
+	}
+}
+
+// U16s encodes a slice of uint16 values in big-endian format.
+func (e *EncBE) U16s(v []uint16) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/2)
+		for i, u := range v[:k] {
+			p := b[2*i : 2*i+2]
+			p[0] = byte(u >> 8)
+			p[1] = byte(u)
+		}
+		e.push(b[:2*k])
+		v = v[k:]
+	}
+}
+
+// U32s encodes a slice of uint32 values in big-endian format.
+func (e *EncBE) U32s(v []uint32) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/4)
+		for i, u := range v[:k] {
+			p := b[4*i : 4*i+4]
+			p[0] = byte(u >> 24)
+			p[1] = byte(u >> 16)
+			p[2] = byte(u >> 8)
+			p[3] = byte(u)
+		}
+		e.push(b[:4*k])
+		v = v[k:]
+	}
+}
+
+// U64s encodes a slice of uint64 values in big-endian format.
+func (e *EncBE) U64s(v []uint64) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/8)
+		for i, u := range v[:k] {
+			p := b[8*i : 8*i+8]
+			p[0] = byte(u >> 56)
+			p[1] = byte(u >> 48)
+			p[2] = byte(u >> 40)
+			p[3] = byte(u >> 32)
+			p[4] = byte(u >> 24)
+			p[5] = byte(u >> 16)
+			p[6] = byte(u >> 8)
+			p[7] = byte(u)
+		}
+		e.push(b[:8*k])
+		v = v[k:]
+	}
+}
+
+// I16s encodes a slice of int16 values in big-endian format.
+func (e *EncBE) I16s(v []int16) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/2)
+		for i, x := range v[:k] {
+			u := uint16(x)
+			p := b[2*i : 2*i+2]
+			p[0] = byte(u >> 8)
+			p[1] = byte(u)
+		}
+		e.push(b[:2*k])
+		v = v[k:]
+	}
+}
+
+// I32s encodes a slice of int32 values in big-endian format.
+func (e *EncBE) I32s(v []int32) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/4)
+		for i, x := range v[:k] {
+			u := uint32(x)
+			p := b[4*i : 4*i+4]
+			p[0] = byte(u >> 24)
+			p[1] = byte(u >> 16)
+			p[2] = byte(u >> 8)
+			p[3] = byte(u)
+		}
+		e.push(b[:4*k])
+		v = v[k:]
+	}
+}
+
+// I64s encodes a slice of int64 values in big-endian format.
+func (e *EncBE) I64s(v []int64) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/8)
+		for i, x := range v[:k] {
+			u := uint64(x)
+			p := b[8*i : 8*i+8]
+			p[0] = byte(u >> 56)
+			p[1] = byte(u >> 48)
+			p[2] = byte(u >> 40)
+			p[3] = byte(u >> 32)
+			p[4] = byte(u >> 24)
+			p[5] = byte(u >> 16)
+			p[6] = byte(u >> 8)
+			p[7] = byte(u)
+		}
+		e.push(b[:8*k])
+		v = v[k:]
+	}
+}
+
+// F32s encodes a slice of float32 values in big-endian format.
+func (e *EncBE) F32s(v []float32) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/4)
+		for i, x := range v[:k] {
+			u := math.Float32bits(x)
+			p := b[4*i : 4*i+4]
+			p[0] = byte(u >> 24)
+			p[1] = byte(u >> 16)
+			p[2] = byte(u >> 8)
+			p[3] = byte(u)
+		}
+		e.push(b[:4*k])
+		v = v[k:]
+	}
+}
+
+// F64s encodes a slice of float64 values in big-endian format.
+func (e *EncBE) F64s(v []float64) {
+	if hostBE {
+		e.push(sliceBytes(v))
+		return
+	}
+	b := e.bulkBuf()
+	for len(v) > 0 && e.Err == nil {
+		k := min(len(v), len(b)/8)
+		for i, x := range v[:k] {
+			u := math.Float64bits(x)
+			p := b[8*i : 8*i+8]
+			p[0] = byte(u >> 56)
+			p[1] = byte(u >> 48)
+			p[2] = byte(u >> 40)
+			p[3] = byte(u >> 32)
+			p[4] = byte(u >> 24)
+			p[5] = byte(u >> 16)
+			p[6] = byte(u >> 8)
+			p[7] = byte(u)
+		}
+		e.push(b[:8*k])
+		v = v[k:]
+	}
+}
+
+// Begin opens a transaction: the bytes encoded until the matching Commit are held back
+// from W, so a failed message never leaves a partial write. Transactions can be nested.
+func (e *EncBE) Begin() {
+	e.txs = append(e.txs, txState{off: len(e.pending), n: e.N, base: e.Base, err: e.Err})
+}
+
+// Commit closes the innermost transaction. When the outermost transaction is committed
+// without error, the held back bytes are written to W. If Err is set, the bytes of the
+// transaction are discarded instead, N and Base are restored and Err is kept.
+func (e *EncBE) Commit() {
+	if len(e.txs) == 0 {
+		e.Err = ErrNoTransaction
+		return
+	}
+	tx := e.txs[len(e.txs)-1]
+	e.txs = e.txs[:len(e.txs)-1]
+	if e.Err != nil {
+		e.pending = e.pending[:tx.off]
+		e.N, e.Base = tx.n, tx.base
+		return
+	}
+	if len(e.txs) > 0 {
+		return
+	}
+	n, err := writeAll(e.W, e.pending)
+	e.N -= int64(len(e.pending) - n)
+	e.pending = e.pending[:0]
+	if err != nil {
+		e.Err = err
+	}
+}
+
+// Rollback closes the innermost transaction, discarding its bytes and restoring N, Base
+// and Err to their values at Begin.
+func (e *EncBE) Rollback() {
+	if len(e.txs) == 0 {
+		e.Err = ErrNoTransaction
+		return
+	}
+	tx := e.txs[len(e.txs)-1]
+	e.txs = e.txs[:len(e.txs)-1]
+	e.pending = e.pending[:tx.off]
+	e.N, e.Base, e.Err = tx.n, tx.base, tx.err
+}
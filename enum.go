@@ -0,0 +1,291 @@
+package bitflux
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrUnknownEnum is reported when a decoded or parsed value is not part of its enum.
+	ErrUnknownEnum = errors.New("bitflux: unknown enum value")
+	// ErrEnumRange is reported when an encoded value does not fit in its enum's width
+	// or a decoded value does not fit in its enum's type.
+	ErrEnumRange = errors.New("bitflux: enum value overflows width")
+)
+
+// Integer is the set of integer types an Enum can be defined on.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Enum ties a Go integer type to its allowed values, their names and their encoded width.
+// It is usually declared once per type and used from the type's methods:
+//
+//	type MsgType uint8
+//
+//	var msgTypes = bitflux.NewEnum("MsgType", 1, map[MsgType]string{1: "Ping", 2: "Pong"})
+//
+//	func (m MsgType) String() string               { return msgTypes.String(m) }
+//	func (m MsgType) MarshalText() ([]byte, error) { return msgTypes.MarshalText(m) }
+type Enum[T Integer] struct {
+	Name  string // Type name used in errors and for unknown values
+	Width int    // Encoded width in bytes: 1, 2, 4 or 8
+
+	// Warn, if set, is called with the error for an unknown decoded value instead of
+	// setting the decoder's Err, so decoding continues.
+	Warn func(err error)
+
+	names  map[T]string
+	values map[string]T
+}
+
+// NewEnum creates an enum named name that is encoded in width bytes and allows the values in names.
+// It panics if width is not 1, 2, 4 or 8, if a value does not fit in width bytes or if two values share a name.
+// Values of signed types are encoded in two's complement.
+func NewEnum[T Integer](name string, width int, names map[T]string) *Enum[T] {
+	switch width {
+	case 1, 2, 4, 8:
+	default:
+		panic("bitflux: invalid enum width " + strconv.Itoa(width))
+	}
+	e := &Enum[T]{
+		Name:   name,
+		Width:  width,
+		names:  make(map[T]string, len(names)),
+		values: make(map[string]T, len(names)),
+	}
+	for v, n := range names {
+		if !e.fits(v) {
+			panic("bitflux: enum value " + e.format(v) + " of " + name + " overflows width " + strconv.Itoa(width))
+		}
+		if _, dup := e.values[n]; dup {
+			panic("bitflux: duplicate enum name " + n)
+		}
+		e.names[v] = n
+		e.values[n] = v
+	}
+	return e
+}
+
+// signed reports whether T is a signed integer type.
+func (e *Enum[T]) signed() bool {
+	var zero T
+	return zero-1 < 0
+}
+
+// fits reports whether v can be encoded in Width bytes.
+func (e *Enum[T]) fits(v T) bool {
+	bits := 8 * e.Width
+	switch {
+	case bits == 64:
+		return true
+	case e.signed():
+		limit := int64(1) << (bits - 1)
+		return int64(v) >= -limit && int64(v) < limit
+	default:
+		return uint64(v) < 1<<bits
+	}
+}
+
+// fromRaw converts a decoded value of Width bytes to T, sign-extending it for signed types.
+// It returns an error wrapping ErrEnumRange if the value does not fit in T.
+func (e *Enum[T]) fromRaw(u uint64) (T, error) {
+	var v T
+	var ok bool
+	if e.signed() {
+		s := int64(u)
+		if shift := 64 - 8*e.Width; shift > 0 {
+			s = int64(u<<shift) >> shift
+		}
+		v = T(s)
+		ok = int64(v) == s
+	} else {
+		v = T(u)
+		ok = uint64(v) == u
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: %s raw value %#x does not fit the type", ErrEnumRange, e.Name, u)
+	}
+	return v, nil
+}
+
+// encodable returns an error if v does not fit in Width bytes, or reports an unknown value like check.
+func (e *Enum[T]) encodable(v T) error {
+	if !e.fits(v) {
+		return fmt.Errorf("%w: %s(%s) in %d bytes", ErrEnumRange, e.Name, e.format(v), e.Width)
+	}
+	return e.check(v)
+}
+
+// Valid reports whether v is an allowed value.
+func (e *Enum[T]) Valid(v T) bool {
+	_, ok := e.names[v]
+	return ok
+}
+
+// Values returns the allowed values in ascending order.
+func (e *Enum[T]) Values() []T {
+	vs := make([]T, 0, len(e.names))
+	for v := range e.names {
+		vs = append(vs, v)
+	}
+	sort.Slice(vs, func(i, j int) bool { return vs[i] < vs[j] })
+	return vs
+}
+
+// String returns the name of v, or Name(v) for unknown values.
+func (e *Enum[T]) String(v T) string {
+	if n, ok := e.names[v]; ok {
+		return n
+	}
+	return e.Name + "(" + e.format(v) + ")"
+}
+
+func (e *Enum[T]) format(v T) string {
+	if v < 0 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// Parse returns the value with the given name.
+func (e *Enum[T]) Parse(name string) (T, error) {
+	if v, ok := e.values[name]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("%w: %s %q", ErrUnknownEnum, e.Name, name)
+}
+
+// MarshalText returns the name of v. Unknown values are an error.
+func (e *Enum[T]) MarshalText(v T) ([]byte, error) {
+	if n, ok := e.names[v]; ok {
+		return []byte(n), nil
+	}
+	return nil, e.unknown(v)
+}
+
+// UnmarshalText parses a name produced by MarshalText into v.
+func (e *Enum[T]) UnmarshalText(text []byte, v *T) error {
+	p, err := e.Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*v = p
+	return nil
+}
+
+func (e *Enum[T]) unknown(v T) error {
+	return fmt.Errorf("%w: %s(%s)", ErrUnknownEnum, e.Name, e.format(v))
+}
+
+// check reports an unknown value through Warn, or returns it as an error.
+func (e *Enum[T]) check(v T) error {
+	if e.Valid(v) {
+		return nil
+	}
+	err := e.unknown(v)
+	if e.Warn != nil {
+		e.Warn(err)
+		return nil
+	}
+	return err
+}
+
+// DecodeLE reads a little-endian value of Width bytes from d.
+// Unknown values are returned as is and set d.Err to an error wrapping ErrUnknownEnum, unless Warn is set.
+// Values that do not fit in T set d.Err to an error wrapping ErrEnumRange.
+func (e *Enum[T]) DecodeLE(d *DecLE) T {
+	var u uint64
+	switch e.Width {
+	case 1:
+		u = uint64(d.U8())
+	case 2:
+		u = uint64(d.U16())
+	case 4:
+		u = uint64(d.U32())
+	default:
+		u = d.U64()
+	}
+	if d.Err != nil {
+		return 0
+	}
+	v, err := e.fromRaw(u)
+	if err == nil {
+		err = e.check(v)
+	}
+	d.Err = err
+	return v
+}
+
+// DecodeBE reads a big-endian value of Width bytes from d.
+// Unknown values are returned as is and set d.Err to an error wrapping ErrUnknownEnum, unless Warn is set.
+// Values that do not fit in T set d.Err to an error wrapping ErrEnumRange.
+func (e *Enum[T]) DecodeBE(d *DecBE) T {
+	var u uint64
+	switch e.Width {
+	case 1:
+		u = uint64(d.U8())
+	case 2:
+		u = uint64(d.U16())
+	case 4:
+		u = uint64(d.U32())
+	default:
+		u = d.U64()
+	}
+	if d.Err != nil {
+		return 0
+	}
+	v, err := e.fromRaw(u)
+	if err == nil {
+		err = e.check(v)
+	}
+	d.Err = err
+	return v
+}
+
+// EncodeLE writes v to enc as a little-endian value of Width bytes.
+// Unknown values set enc.Err to an error wrapping ErrUnknownEnum, unless Warn is set,
+// and values that do not fit in Width bytes set it to an error wrapping ErrEnumRange.
+func (e *Enum[T]) EncodeLE(enc *EncLE, v T) {
+	if enc.Err != nil {
+		return
+	}
+	if enc.Err = e.encodable(v); enc.Err != nil {
+		return
+	}
+	switch u := uint64(v); e.Width {
+	case 1:
+		enc.U8(uint8(u))
+	case 2:
+		enc.U16(uint16(u))
+	case 4:
+		enc.U32(uint32(u))
+	default:
+		enc.U64(u)
+	}
+}
+
+// EncodeBE writes v to enc as a big-endian value of Width bytes.
+// Unknown values set enc.Err to an error wrapping ErrUnknownEnum, unless Warn is set,
+// and values that do not fit in Width bytes set it to an error wrapping ErrEnumRange.
+func (e *Enum[T]) EncodeBE(enc *EncBE, v T) {
+	if enc.Err != nil {
+		return
+	}
+	if enc.Err = e.encodable(v); enc.Err != nil {
+		return
+	}
+	switch u := uint64(v); e.Width {
+	case 1:
+		enc.U8(uint8(u))
+	case 2:
+		enc.U16(uint16(u))
+	case 4:
+		enc.U32(uint32(u))
+	default:
+		enc.U64(u)
+	}
+}
@@ -0,0 +1,174 @@
+package bitflux
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type testMsgType uint8
+
+var testMsgTypes = NewEnum("MsgType", 1, map[testMsgType]string{1: "Ping", 2: "Pong", 7: "Data"})
+
+func (m testMsgType) String() string               { return testMsgTypes.String(m) }
+func (m testMsgType) MarshalText() ([]byte, error) { return testMsgTypes.MarshalText(m) }
+func (m *testMsgType) UnmarshalText(b []byte) error {
+	return testMsgTypes.UnmarshalText(b, m)
+}
+
+func TestEnumDecode(t *testing.T) {
+	dec := NewDecBE(bytes.NewReader([]byte{0x02, 0x09, 0x01}))
+	if v := testMsgTypes.DecodeBE(dec); v != 2 || dec.Err != nil {
+		t.Fatalf("got=%v err=%v, want=Pong", v, dec.Err)
+	}
+	if v := testMsgTypes.DecodeBE(dec); v != 9 {
+		t.Fatalf("got=%d, want=9", v)
+	}
+	if !errors.Is(dec.Err, ErrUnknownEnum) {
+		t.Fatalf("expected ErrUnknownEnum, got %v", dec.Err)
+	}
+	if got := dec.Err.Error(); got != "bitflux: unknown enum value: MsgType(9)" {
+		t.Errorf("unexpected error text %q", got)
+	}
+}
+
+func TestEnumWarn(t *testing.T) {
+	type level int16
+	var warnings []error
+	levels := NewEnum("Level", 2, map[level]string{-1: "Low", 1: "High"})
+	levels.Warn = func(err error) { warnings = append(warnings, err) }
+
+	dec := NewDecLE(bytes.NewReader([]byte{0xFF, 0xFF, 0x05, 0x00, 0x01, 0x00}))
+	got := []level{levels.DecodeLE(dec), levels.DecodeLE(dec), levels.DecodeLE(dec)}
+	if dec.Err != nil {
+		t.Fatalf("unexpected error: %v", dec.Err)
+	}
+	if got[0] != -1 || got[1] != 5 || got[2] != 1 {
+		t.Fatalf("got=%v", got)
+	}
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrUnknownEnum) {
+		t.Fatalf("warnings: got=%v", warnings)
+	}
+}
+
+func TestEnumEncode(t *testing.T) {
+	types := NewEnum("Wide", 4, map[uint32]string{0x01020304: "A"})
+	var buf bytes.Buffer
+	enc := NewEncBE(&buf)
+	types.EncodeBE(enc, 0x01020304)
+	if !bytes.Equal(buf.Bytes(), []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+	types.EncodeBE(enc, 5)
+	if !errors.Is(enc.Err, ErrUnknownEnum) {
+		t.Fatalf("expected ErrUnknownEnum, got %v", enc.Err)
+	}
+	if buf.Len() != 4 {
+		t.Errorf("unknown value was written")
+	}
+}
+
+func TestEnumSigned(t *testing.T) {
+	type level int
+	levels := NewEnum("Level", 1, map[level]string{-1: "Low", 1: "High", -128: "Min"})
+	var buf bytes.Buffer
+	le := NewEncLE(&buf)
+	for _, v := range []level{-1, 1, -128} {
+		levels.EncodeLE(le, v)
+	}
+	if le.Err != nil || !bytes.Equal(buf.Bytes(), []byte{0xFF, 0x01, 0x80}) {
+		t.Fatalf("EncodeLE: got=% x err=%v", buf.Bytes(), le.Err)
+	}
+	dec := NewDecLE(&buf)
+	if got := []level{levels.DecodeLE(dec), levels.DecodeLE(dec), levels.DecodeLE(dec)}; dec.Err != nil || got[0] != -1 || got[1] != 1 || got[2] != -128 {
+		t.Fatalf("DecodeLE: got=%v err=%v", got, dec.Err)
+	}
+
+	wide := NewEnum("Wide", 4, map[int64]string{-2: "Minus2"})
+	be := NewEncBE(&buf)
+	wide.EncodeBE(be, -2)
+	if be.Err != nil || !bytes.Equal(buf.Bytes(), []byte{0xFF, 0xFF, 0xFF, 0xFE}) {
+		t.Fatalf("EncodeBE: got=% x err=%v", buf.Bytes(), be.Err)
+	}
+	if v := wide.DecodeBE(NewDecBE(&buf)); v != -2 {
+		t.Fatalf("DecodeBE: got=%d", v)
+	}
+}
+
+func TestEnumRange(t *testing.T) {
+	for name, f := range map[string]func(){
+		"Unsigned": func() { NewEnum("Big", 1, map[uint16]string{300: "X"}) },
+		"Signed":   func() { NewEnum("Level", 1, map[int]string{128: "X"}) },
+		"Negative": func() { NewEnum("Level", 2, map[int]string{-32769: "X"}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewEnum did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+
+	big := NewEnum("Big", 1, map[uint16]string{1: "One"})
+	big.Warn = func(error) {}
+	var buf bytes.Buffer
+	enc := NewEncLE(&buf)
+	if big.EncodeLE(enc, 300); !errors.Is(enc.Err, ErrEnumRange) || buf.Len() != 0 {
+		t.Fatalf("EncodeLE(300): got=% x err=%v", buf.Bytes(), enc.Err)
+	}
+	levels := NewEnum("Level", 1, map[int]string{-1: "Low"})
+	be := NewEncBE(&buf)
+	if levels.EncodeBE(be, -129); !errors.Is(be.Err, ErrEnumRange) || buf.Len() != 0 {
+		t.Fatalf("EncodeBE(-129): got=% x err=%v", buf.Bytes(), be.Err)
+	}
+
+	// Wire values wider than the Go type must not be truncated into a known member.
+	narrow := NewEnum("X", 2, map[uint8]string{1: "A"})
+	narrow.Warn = func(error) {}
+	if v := narrow.DecodeBE(NewDecBE(bytes.NewReader([]byte{0x01, 0x01}))); v != 0 {
+		t.Errorf("DecodeBE(0x0101): got=%d, want=0", v)
+	}
+	d := NewDecLE(bytes.NewReader([]byte{0x01, 0x01}))
+	if narrow.DecodeLE(d); !errors.Is(d.Err, ErrEnumRange) {
+		t.Errorf("DecodeLE(0x0101): expected ErrEnumRange, got %v", d.Err)
+	}
+	small := NewEnum("Small", 2, map[int8]string{-1: "Low"})
+	d = NewDecLE(bytes.NewReader([]byte{0xFF, 0xFF, 0x7F, 0xFF}))
+	if v := small.DecodeLE(d); v != -1 || d.Err != nil {
+		t.Fatalf("DecodeLE(0xffff): got=%d err=%v, want=-1", v, d.Err)
+	}
+	if small.DecodeLE(d); !errors.Is(d.Err, ErrEnumRange) {
+		t.Errorf("DecodeLE(0xff7f): expected ErrEnumRange, got %v", d.Err)
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	if s := testMsgType(7).String(); s != "Data" {
+		t.Errorf("String: got=%q, want=Data", s)
+	}
+	if s := testMsgType(3).String(); s != "MsgType(3)" {
+		t.Errorf("String: got=%q, want=MsgType(3)", s)
+	}
+
+	out, err := json.Marshal(map[string]testMsgType{"type": 1})
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v", err)
+	}
+	if string(out) != `{"type":"Ping"}` {
+		t.Fatalf("json: got=%s", out)
+	}
+	var in map[string]testMsgType
+	if err := json.Unmarshal([]byte(`{"type":"Data"}`), &in); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	if in["type"] != 7 {
+		t.Errorf("json: got=%d, want=7", in["type"])
+	}
+
+	if vs := testMsgTypes.Values(); len(vs) != 3 || vs[0] != 1 || vs[2] != 7 {
+		t.Errorf("Values: got=%v", vs)
+	}
+}
@@ -0,0 +1,42 @@
+package bitflux
+
+import "errors"
+
+// ErrBufferFull is reported by the fixed-capacity encoders when a write would overflow their buffer.
+var ErrBufferFull = errors.New("bitflux: buffer full")
+
+// fixedWriter is an io.Writer that appends to a slice without growing it.
+// A write that does not fit is rejected as a whole with ErrBufferFull.
+type fixedWriter struct{ buf *[]byte }
+
+func (w fixedWriter) Write(p []byte) (int, error) {
+	if cap(*w.buf)-len(*w.buf) < len(p) {
+		return 0, ErrBufferFull
+	}
+	*w.buf = append(*w.buf, p...)
+	return len(p), nil
+}
+
+// fixedTx is the encoder state saved by Begin in the fixed-capacity encoders.
+type fixedTx struct {
+	len  int   // length of Buf at Begin
+	n    int64 // encoder N at Begin
+	base int64 // encoder Base at Begin
+	err  error // encoder Err at Begin
+}
+
+// fixedTransaction tracks the open transactions of a fixed-capacity encoder.
+// Their bytes are written in place, so closing one only keeps or truncates them.
+type fixedTransaction struct {
+	txs []fixedTx // open transactions, innermost last
+}
+
+// pop removes the innermost transaction, reporting false if none is open.
+func (t *fixedTransaction) pop() (fixedTx, bool) {
+	if len(t.txs) == 0 {
+		return fixedTx{}, false
+	}
+	tx := t.txs[len(t.txs)-1]
+	t.txs = t.txs[:len(t.txs)-1]
+	return tx, true
+}
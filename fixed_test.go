@@ -0,0 +1,168 @@
+package bitflux
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFixedEncMatchesEnc(t *testing.T) {
+	var arr [256]byte
+	fle := FixedEncLE{Buf: arr[:0]}
+	var le bytes.Buffer
+	enc := NewEncLE(&le)
+
+	fle.U8(0x01)
+	enc.U8(0x01)
+	fle.Align(4)
+	enc.Align(4)
+	fle.I16(-2)
+	enc.I16(-2)
+	fle.F64(3.25)
+	enc.F64(3.25)
+	fle.U32s([]uint32{1, 2, 3})
+	enc.U32s([]uint32{1, 2, 3})
+	fle.Fill = 0xEE
+	fle.PadTo(40)
+	enc.Fill = 0xEE
+	enc.PadTo(40)
+	fle.Write([]byte("end"))
+	enc.Write([]byte("end"))
+
+	if fle.Err != nil {
+		t.Fatalf("unexpected error: %v", fle.Err)
+	}
+	if !bytes.Equal(fle.Bytes(), le.Bytes()) {
+		t.Fatalf("got=% x, want=% x", fle.Bytes(), le.Bytes())
+	}
+	if fle.N != enc.N {
+		t.Errorf("N: got=%d, want=%d", fle.N, enc.N)
+	}
+
+	fbe := NewFixedEncBE(make([]byte, 32))
+	var be bytes.Buffer
+	encBE := NewEncBE(&be)
+	fbe.U16(0x0102)
+	encBE.U16(0x0102)
+	fbe.F32s([]float32{1.5, -2})
+	encBE.F32s([]float32{1.5, -2})
+	fbe.I64(-1)
+	encBE.I64(-1)
+	if !bytes.Equal(fbe.Bytes(), be.Bytes()) {
+		t.Fatalf("got=% x, want=% x", fbe.Bytes(), be.Bytes())
+	}
+}
+
+func TestFixedEncMethodParity(t *testing.T) {
+	for _, pair := range [][2]reflect.Type{
+		{reflect.TypeOf(&EncLE{}), reflect.TypeOf(&FixedEncLE{})},
+		{reflect.TypeOf(&EncBE{}), reflect.TypeOf(&FixedEncBE{})},
+	} {
+		for i := 0; i < pair[0].NumMethod(); i++ {
+			m := pair[0].Method(i)
+			fm, ok := pair[1].MethodByName(m.Name)
+			switch {
+			case !ok:
+				t.Errorf("%v has no method %s", pair[1], m.Name)
+			case m.Name == "Reset":
+				// Reset takes the buffer to write into rather than an io.Writer.
+			case !sameSignature(m.Type, fm.Type):
+				t.Errorf("%v.%s: got %v, want %v", pair[1], m.Name, fm.Type, m.Type)
+			}
+		}
+	}
+}
+
+// sameSignature reports whether method types a and b match apart from their receivers.
+func sameSignature(a, b reflect.Type) bool {
+	if a.NumIn() != b.NumIn() || a.NumOut() != b.NumOut() || a.IsVariadic() != b.IsVariadic() {
+		return false
+	}
+	for i := 1; i < a.NumIn(); i++ {
+		if a.In(i) != b.In(i) {
+			return false
+		}
+	}
+	for i := 0; i < a.NumOut(); i++ {
+		if a.Out(i) != b.Out(i) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFixedEncTransaction(t *testing.T) {
+	var arr [8]byte
+	e := FixedEncLE{Buf: arr[:0]}
+	e.U16(0x0102)
+	e.Begin()
+	e.U16(0x0304)
+	e.Begin()
+	e.U32(0x05060708)
+	e.U32(0x090A0B0C) // overflows the buffer
+	e.Commit()
+	if e.Err != ErrBufferFull || !bytes.Equal(e.Bytes(), []byte{0x02, 0x01, 0x04, 0x03}) || e.N != 4 {
+		t.Fatalf("failed Commit: got=% x N=%d err=%v", e.Bytes(), e.N, e.Err)
+	}
+	e.Rollback()
+	if e.Err != nil || !bytes.Equal(e.Bytes(), []byte{0x02, 0x01}) || e.N != 2 {
+		t.Fatalf("Rollback: got=% x N=%d err=%v", e.Bytes(), e.N, e.Err)
+	}
+
+	e.Begin()
+	e.MarkBase()
+	e.U64(0) // overflows the buffer
+	e.Commit()
+	if e.Err != ErrBufferFull || e.Base != 0 || e.N != 2 {
+		t.Fatalf("failed Commit after MarkBase: Base=%d N=%d err=%v", e.Base, e.N, e.Err)
+	}
+
+	be := NewFixedEncBE(arr[:])
+	be.Begin()
+	be.U16(0x0102)
+	be.Commit()
+	if be.Err != nil || !bytes.Equal(be.Bytes(), []byte{0x01, 0x02}) {
+		t.Fatalf("Commit: got=% x err=%v", be.Bytes(), be.Err)
+	}
+	if be.Commit(); be.Err != ErrNoTransaction {
+		t.Fatalf("Commit without Begin: got err=%v", be.Err)
+	}
+}
+
+func TestFixedEncBufferFull(t *testing.T) {
+	var arr [6]byte
+	e := FixedEncBE{Buf: arr[:0]}
+	e.U32(0x01020304)
+	e.U32(0x05060708)
+	if e.Err != ErrBufferFull {
+		t.Fatalf("expected ErrBufferFull, got %v", e.Err)
+	}
+	if !bytes.Equal(e.Bytes(), []byte{0x01, 0x02, 0x03, 0x04}) {
+		t.Fatalf("got=% x, want=01 02 03 04", e.Bytes())
+	}
+	if e.N != 4 {
+		t.Errorf("N: got=%d, want=4", e.N)
+	}
+
+	e.Reset(arr[:])
+	e.U16(0xAABB)
+	if e.Err != nil || !bytes.Equal(e.Bytes(), []byte{0xAA, 0xBB}) {
+		t.Fatalf("after Reset: got=% x err=%v", e.Bytes(), e.Err)
+	}
+}
+
+func TestFixedEncNoAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		var arr [256]byte
+		e := FixedEncBE{Buf: arr[:0]}
+		e.U16(0x0102)
+		e.U32(0x03040506)
+		e.F64(1.5)
+		e.Align(8)
+		e.U16s(testMessageWords)
+		_ = e.Bytes()
+	})
+	if allocs != 0 {
+		t.Fatalf("got %v allocs, want 0", allocs)
+	}
+}
@@ -0,0 +1,338 @@
+package bitflux
+
+import (
+	"encoding"
+	"io"
+	"math"
+)
+
+// FixedEncBE is a big-endian binary encoder that writes into a caller-provided buffer.
+// The buffer never grows: a write that would overflow it is discarded and sets Err to ErrBufferFull.
+// It has the same methods as EncBE, except that Reset takes the buffer to write into
+// rather than an io.Writer. It can be used as a value and encoding does not allocate;
+// only Begin allocates, when transactions nest deeper than before the last Reset:
+//
+//	var arr [256]byte
+//	e := FixedEncBE{Buf: arr[:0]}
+type FixedEncBE struct {
+	Buf []byte // Written bytes; cap(Buf) is the capacity of the encoder
+	N   int64  // Number of bytes written
+	Err error  // First error encountered during encoding
+
+	Base int64 // Offset that Align and PadTo are relative to, see MarkBase
+	Fill byte  // Value of the pad bytes written by Align and PadTo
+
+	fixedTransaction
+}
+
+// NewFixedEncBE creates a new big-endian encoder that writes into buf, starting at buf[:0].
+func NewFixedEncBE(buf []byte) *FixedEncBE { return &FixedEncBE{Buf: buf[:0]} }
+
+// Reset makes the encoder write into buf, starting at buf[:0], and clears N, Err, Base and open transactions.
+func (e *FixedEncBE) Reset(buf []byte) {
+	e.Buf = buf[:0]
+	e.N = 0
+	e.Err = nil
+	e.Base = 0
+	e.txs = e.txs[:0]
+}
+
+// Bytes returns the bytes written so far.
+func (e *FixedEncBE) Bytes() []byte { return e.Buf }
+
+// grow extends Buf by n bytes and returns them for writing.
+// It returns nil and sets Err to ErrBufferFull if they do not fit.
+func (e *FixedEncBE) grow(n int64) []byte {
+	if e.Err != nil {
+		return nil
+	}
+	l := len(e.Buf)
+	if n > int64(cap(e.Buf)-l) {
+		e.Err = ErrBufferFull
+		return nil
+	}
+	e.Buf = e.Buf[:l+int(n)]
+	e.N += n
+	return e.Buf[l:]
+}
+
+// U8 encodes a uint8 value in big-endian format.
+func (e *FixedEncBE) U8(v uint8) {
+	if b := e.grow(1); b != nil {
+		AppendU8(b[:0], v)
+	}
+}
+
+// U16 encodes a uint16 value in big-endian format.
+func (e *FixedEncBE) U16(v uint16) {
+	if b := e.grow(2); b != nil {
+		AppendU16BE(b[:0], v)
+	}
+}
+
+// U32 encodes a uint32 value in big-endian format.
+func (e *FixedEncBE) U32(v uint32) {
+	if b := e.grow(4); b != nil {
+		AppendU32BE(b[:0], v)
+	}
+}
+
+// U64 encodes a uint64 value in big-endian format.
+func (e *FixedEncBE) U64(v uint64) {
+	if b := e.grow(8); b != nil {
+		AppendU64BE(b[:0], v)
+	}
+}
+
+// I8 encodes an int8 value in big-endian format.
+func (e *FixedEncBE) I8(v int8) {
+	e.U8(uint8(v))
+}
+
+// I16 encodes an int16 value in big-endian format.
+func (e *FixedEncBE) I16(v int16) {
+	e.U16(uint16(v))
+}
+
+// I32 encodes an int32 value in big-endian format.
+func (e *FixedEncBE) I32(v int32) {
+	e.U32(uint32(v))
+}
+
+// I64 encodes an int64 value in big-endian format.
+func (e *FixedEncBE) I64(v int64) {
+	e.U64(uint64(v))
+}
+
+// F32 encodes a float32 value in big-endian format using IEEE 754 representation.
+func (e *FixedEncBE) F32(v float32) {
+	e.U32(math.Float32bits(v))
+}
+
+// F64 encodes a float64 value in big-endian format using IEEE 754 representation.
+func (e *FixedEncBE) F64(v float64) {
+	e.U64(math.Float64bits(v))
+}
+
+// Write writes raw bytes to the encoder.
+func (e *FixedEncBE) Write(p []byte) {
+	if b := e.grow(int64(len(p))); b != nil {
+		copy(b, p)
+	}
+}
+
+// To calls WriteTo on the provided WriterTo and updates the encoder's byte count and error state.
+func (e *FixedEncBE) To(w io.WriterTo) {
+	if e.Err != nil {
+		return
+	}
+	n, err := w.WriteTo(fixedWriter{&e.Buf})
+	e.N += n
+	if err != nil {
+		e.Err = err
+	}
+}
+
+// Marshal calls MarshalBinary on the provided BinaryMarshaler and writes the result to the encoder.
+// It updates the encoder's byte count and error state.
+func (e *FixedEncBE) Marshal(m encoding.BinaryMarshaler) {
+	if e.Err != nil {
+		return
+	}
+	buf, err := m.MarshalBinary()
+	if err != nil {
+		e.Err = err
+		return
+	}
+	e.Write(buf)
+}
+
+// MarkBase makes the current position the base offset for Align and PadTo.
+func (e *FixedEncBE) MarkBase() { e.Base = e.N }
+
+// Align writes pad bytes until the position relative to Base is a multiple of n.
+func (e *FixedEncBE) Align(n int) {
+	e.pad(alignPad(e.N-e.Base, n))
+}
+
+// PadTo writes pad bytes until the position relative to Base reaches offset.
+// It sets Err to ErrPadOverrun if the position is already past offset.
+func (e *FixedEncBE) PadTo(offset int64) {
+	if e.Err != nil {
+		return
+	}
+	n := offset - (e.N - e.Base)
+	if n < 0 {
+		e.Err = ErrPadOverrun
+		return
+	}
+	e.pad(n)
+}
+
+// pad writes n Fill bytes to the encoder.
+func (e *FixedEncBE) pad(n int64) {
+	b := e.grow(n)
+	for i := range b {
+		b[i] = e.Fill
+	}
+}
+
+// U16s encodes a slice of uint16 values in big-endian format.
+func (e *FixedEncBE) U16s(v []uint16) {
+	b := e.grow(int64(2 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendU16BE(b, x)
+	}
+}
+
+// U32s encodes a slice of uint32 values in big-endian format.
+func (e *FixedEncBE) U32s(v []uint32) {
+	b := e.grow(int64(4 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendU32BE(b, x)
+	}
+}
+
+// U64s encodes a slice of uint64 values in big-endian format.
+func (e *FixedEncBE) U64s(v []uint64) {
+	b := e.grow(int64(8 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendU64BE(b, x)
+	}
+}
+
+// I16s encodes a slice of int16 values in big-endian format.
+func (e *FixedEncBE) I16s(v []int16) {
+	b := e.grow(int64(2 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendI16BE(b, x)
+	}
+}
+
+// I32s encodes a slice of int32 values in big-endian format.
+func (e *FixedEncBE) I32s(v []int32) {
+	b := e.grow(int64(4 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendI32BE(b, x)
+	}
+}
+
+// I64s encodes a slice of int64 values in big-endian format.
+func (e *FixedEncBE) I64s(v []int64) {
+	b := e.grow(int64(8 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendI64BE(b, x)
+	}
+}
+
+// F32s encodes a slice of float32 values in big-endian format.
+func (e *FixedEncBE) F32s(v []float32) {
+	b := e.grow(int64(4 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendF32BE(b, x)
+	}
+}
+
+// F64s encodes a slice of float64 values in big-endian format.
+func (e *FixedEncBE) F64s(v []float64) {
+	b := e.grow(int64(8 * len(v)))
+	if b == nil {
+		return
+	}
+	if hostBE {
+		copy(b, sliceBytes(v))
+		return
+	}
+	b = b[:0]
+	for _, x := range v {
+		b = AppendF64BE(b, x)
+	}
+}
+
+// Begin opens a transaction. The bytes encoded until the matching Commit stay in Buf
+// only if the transaction succeeds. Transactions can be nested.
+func (e *FixedEncBE) Begin() {
+	e.txs = append(e.txs, fixedTx{len: len(e.Buf), n: e.N, base: e.Base, err: e.Err})
+}
+
+// Commit closes the innermost transaction. If Err is set, the bytes of the transaction
+// are removed from Buf, N and Base are restored and Err is kept.
+func (e *FixedEncBE) Commit() {
+	tx, ok := e.pop()
+	if !ok {
+		e.Err = ErrNoTransaction
+		return
+	}
+	if e.Err != nil {
+		e.Buf = e.Buf[:tx.len]
+		e.N, e.Base = tx.n, tx.base
+	}
+}
+
+// Rollback closes the innermost transaction, removing its bytes from Buf and restoring
+// N, Base and Err to their values at Begin.
+func (e *FixedEncBE) Rollback() {
+	tx, ok := e.pop()
+	if !ok {
+		e.Err = ErrNoTransaction
+		return
+	}
+	e.Buf = e.Buf[:tx.len]
+	e.N, e.Base, e.Err = tx.n, tx.base, tx.err
+}
@@ -0,0 +1,25 @@
+package bitflux
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultMaxFrame is the maximum decoded frame size used by the frame readers
+// when no explicit limit is configured.
+const DefaultMaxFrame = 64 * 1024
+
+// FrameStats counts frames seen by a frame reader.
+type FrameStats struct {
+	Frames    int64 // Frames decoded successfully
+	Dropped   int64 // Frames discarded because they were malformed or truncated
+	Oversized int64 // Frames discarded because they exceeded MaxFrame
+}
+
+// byteReader returns r as an io.ByteReader, wrapping it in a bufio.Reader if needed.
+func byteReader(r io.Reader) io.ByteReader {
+	if br, ok := r.(io.ByteReader); ok {
+		return br
+	}
+	return bufio.NewReader(r)
+}
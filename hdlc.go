@@ -0,0 +1,205 @@
+package bitflux
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+)
+
+// HDLC special characters (RFC 1662).
+const (
+	hdlcFlag = 0x7E
+	hdlcEsc  = 0x7D
+	hdlcXor  = 0x20
+)
+
+// FCS selects the frame check sequence appended to HDLC frames.
+type FCS int
+
+const (
+	FCSNone FCS = iota // No frame check sequence
+	FCS16              // 16-bit FCS (CRC-16/X-25), transmitted least significant byte first
+	FCS32              // 32-bit FCS (CRC-32), transmitted least significant byte first
+)
+
+// Size returns the number of bytes the frame check sequence occupies on the wire.
+func (f FCS) Size() int {
+	switch f {
+	case FCS16:
+		return 2
+	case FCS32:
+		return 4
+	}
+	return 0
+}
+
+// appendFCS computes the frame check sequence of p and appends it to dst.
+func (f FCS) appendFCS(dst, p []byte) []byte {
+	switch f {
+	case FCS16:
+		v := fcs16(p)
+		return append(dst, byte(v), byte(v>>8))
+	case FCS32:
+		v := crc32.ChecksumIEEE(p)
+		return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return dst
+}
+
+var fcs16Table = func() (t [256]uint16) {
+	for i := range t {
+		v := uint16(i)
+		for k := 0; k < 8; k++ {
+			if v&1 != 0 {
+				v = v>>1 ^ 0x8408
+			} else {
+				v >>= 1
+			}
+		}
+		t[i] = v
+	}
+	return t
+}()
+
+// fcs16 computes the CRC-16/X-25 frame check sequence used by HDLC and PPP.
+func fcs16(p []byte) uint16 {
+	v := uint16(0xFFFF)
+	for _, c := range p {
+		v = v>>8 ^ fcs16Table[byte(v)^c]
+	}
+	return ^v
+}
+
+// HDLCWriter writes HDLC-like (RFC 1662) byte-stuffed frames to an io.Writer.
+// Each frame is delimited by 0x7E flags and followed by the configured frame check sequence.
+type HDLCWriter struct {
+	W   io.Writer // The underlying writer to write frames to
+	N   int64     // Number of bytes written, including escapes and flags
+	Err error     // First error encountered during framing
+	FCS FCS       // Frame check sequence appended to each frame
+	fcs []byte
+	buf []byte
+}
+
+// NewHDLCWriter creates a new HDLC frame writer that writes to the provided io.Writer.
+func NewHDLCWriter(w io.Writer, fcs FCS) *HDLCWriter { return &HDLCWriter{W: w, FCS: fcs} }
+
+// WriteFrame appends the frame check sequence to p, stuffs the result and writes it as a single frame.
+func (h *HDLCWriter) WriteFrame(p []byte) error {
+	if h.Err != nil {
+		return h.Err
+	}
+	h.fcs = h.FCS.appendFCS(h.fcs[:0], p)
+	h.buf = append(h.buf[:0], hdlcFlag)
+	h.buf = hdlcStuff(h.buf, p)
+	h.buf = hdlcStuff(h.buf, h.fcs)
+	h.buf = append(h.buf, hdlcFlag)
+	n, err := h.W.Write(h.buf)
+	h.N += int64(n)
+	if err == nil && n < len(h.buf) {
+		err = io.ErrShortWrite
+	}
+	h.Err = err
+	return err
+}
+
+// Write implements io.Writer by writing p as a single frame.
+func (h *HDLCWriter) Write(p []byte) (int, error) {
+	if err := h.WriteFrame(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func hdlcStuff(dst, p []byte) []byte {
+	for _, c := range p {
+		if c == hdlcFlag || c == hdlcEsc {
+			dst = append(dst, hdlcEsc, c^hdlcXor)
+		} else {
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// HDLCReader reads HDLC-like (RFC 1662) byte-stuffed frames from an io.Reader, one frame at a time.
+// Frames with an invalid frame check sequence, an abort sequence or that are too short are
+// discarded and counted in Stats; reading resynchronizes on the next flag.
+type HDLCReader struct {
+	R        io.ByteReader // The underlying reader to read frames from
+	MaxFrame int           // Maximum frame size, excluding the FCS; frames larger than this are dropped
+	FCS      FCS           // Frame check sequence expected at the end of each frame
+	Stats    FrameStats    // Frame statistics
+	Err      error         // First error encountered while reading
+	frame    []byte
+	fcs      []byte
+}
+
+// NewHDLCReader creates a new HDLC frame reader that reads from the provided io.Reader.
+// If r does not implement io.ByteReader it is wrapped in a bufio.Reader.
+func NewHDLCReader(r io.Reader, fcs FCS) *HDLCReader {
+	return &HDLCReader{R: byteReader(r), MaxFrame: DefaultMaxFrame, FCS: fcs}
+}
+
+// ReadFrame returns the payload of the next valid frame with the FCS removed.
+// The returned slice is only valid until the next call to ReadFrame.
+// A partial frame at the end of the stream is counted as dropped and io.ErrUnexpectedEOF is returned.
+func (h *HDLCReader) ReadFrame() ([]byte, error) {
+	if h.Err != nil {
+		return nil, h.Err
+	}
+	size := h.FCS.Size()
+	h.frame = h.frame[:0]
+	var esc, over bool
+	for {
+		c, err := h.R.ReadByte()
+		if err != nil {
+			if len(h.frame) > 0 || esc || over {
+				h.Stats.Dropped++
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			h.Err = err
+			return nil, err
+		}
+		if c == hdlcFlag {
+			switch {
+			case over:
+				h.Stats.Oversized++
+			case esc: // abort sequence
+				h.Stats.Dropped++
+			case len(h.frame) == 0:
+				// Back-to-back flags between frames.
+			case len(h.frame) < size:
+				h.Stats.Dropped++
+			default:
+				payload := h.frame[:len(h.frame)-size]
+				h.fcs = h.FCS.appendFCS(h.fcs[:0], payload)
+				if bytes.Equal(h.fcs, h.frame[len(payload):]) {
+					h.Stats.Frames++
+					return payload, nil
+				}
+				h.Stats.Dropped++
+			}
+			h.frame = h.frame[:0]
+			esc, over = false, false
+			continue
+		}
+		if over {
+			continue
+		}
+		if esc {
+			esc = false
+			c ^= hdlcXor
+		} else if c == hdlcEsc {
+			esc = true
+			continue
+		}
+		if h.MaxFrame > 0 && len(h.frame) >= h.MaxFrame+size {
+			over = true
+			continue
+		}
+		h.frame = append(h.frame, c)
+	}
+}
@@ -0,0 +1,89 @@
+package bitflux
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestFCS16CheckValue(t *testing.T) {
+	if got := fcs16([]byte("123456789")); got != 0x906E {
+		t.Fatalf("got=%#04x, want=0x906e", got)
+	}
+}
+
+func TestHDLCWriteFrame(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewHDLCWriter(&buf, FCSNone)
+	if err := w.WriteFrame([]byte{0x01, 0x7E, 0x02, 0x7D, 0x03}); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+	expected := []byte{0x7E, 0x01, 0x7D, 0x5E, 0x02, 0x7D, 0x5D, 0x03, 0x7E}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("got=% x, want=% x", buf.Bytes(), expected)
+	}
+}
+
+func TestHDLCRoundTrip(t *testing.T) {
+	frames := [][]byte{
+		{0xFF, 0x03, 0xC0, 0x21},
+		{0x7E, 0x7D, 0x5E, 0x5D},
+		{0x00},
+	}
+	for _, fcs := range []FCS{FCSNone, FCS16, FCS32} {
+		var buf bytes.Buffer
+		w := NewHDLCWriter(&buf, fcs)
+		for _, f := range frames {
+			w.WriteFrame(f)
+		}
+		if w.Err != nil {
+			t.Fatalf("fcs=%d: unexpected write error: %v", fcs, w.Err)
+		}
+
+		r := NewHDLCReader(&buf, fcs)
+		for i, want := range frames {
+			got, err := r.ReadFrame()
+			if err != nil {
+				t.Fatalf("fcs=%d frame %d: unexpected error: %v", fcs, i, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("fcs=%d frame %d: got=% x, want=% x", fcs, i, got, want)
+			}
+		}
+		if _, err := r.ReadFrame(); err != io.EOF {
+			t.Fatalf("fcs=%d: expected io.EOF, got %v", fcs, err)
+		}
+	}
+}
+
+func TestHDLCReaderResync(t *testing.T) {
+	var buf bytes.Buffer
+	// Tail of a frame captured mid-stream.
+	buf.Write([]byte{0x12, 0x34})
+	w := NewHDLCWriter(&buf, FCS16)
+	w.WriteFrame([]byte{0x01, 0x02, 0x03})
+	corrupt := buf.Len() - 3
+	w.WriteFrame([]byte{0x04, 0x05, 0x06})
+	w.WriteFrame([]byte{0x07, 0x08})
+	// Abort sequence followed by a good frame.
+	buf.Write([]byte{0x7E, 0x09, 0x7D, 0x7E})
+	w.WriteFrame([]byte{0x0A})
+
+	stream := buf.Bytes()
+	stream[corrupt] ^= 0xFF
+
+	r := NewHDLCReader(bytes.NewReader(stream), FCS16)
+	for _, want := range [][]byte{{0x04, 0x05, 0x06}, {0x07, 0x08}, {0x0A}} {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got=% x, want=% x", got, want)
+		}
+	}
+	want := FrameStats{Frames: 3, Dropped: 3}
+	if r.Stats != want {
+		t.Errorf("Stats: got=%+v, want=%+v", r.Stats, want)
+	}
+}
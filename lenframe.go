@@ -0,0 +1,280 @@
+package bitflux
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	// ErrFrameTooLarge is reported when a frame exceeds the configured maximum size
+	// or cannot be represented by the length header.
+	ErrFrameTooLarge = errors.New("bitflux: frame exceeds maximum size")
+	// ErrFrameLength is reported when a length header describes a negative payload size.
+	ErrFrameLength = errors.New("bitflux: invalid frame length")
+	// ErrHeaderWidth is reported when a length header width is outside 1 to 8 bytes.
+	ErrHeaderWidth = errors.New("bitflux: invalid length header width")
+)
+
+// LengthPrefix describes a length header that precedes each frame in a stream.
+//
+// The payload size is computed from the header value as
+// value + Adjust, minus Width when IncludesHeader is set.
+type LengthPrefix struct {
+	Width          int  // Width of the length header in bytes (1 to 8)
+	BigEndian      bool // Length header is encoded in big-endian byte order
+	IncludesHeader bool // Length value counts the header bytes as well as the payload
+	Adjust         int  // Added to the length value to obtain the payload size
+	MaxFrame       int  // Maximum payload size; 0 means DefaultMaxFrame, negative means no limit
+}
+
+func (f LengthPrefix) limit() int {
+	if f.MaxFrame == 0 {
+		return DefaultMaxFrame
+	}
+	return f.MaxFrame
+}
+
+// payloadLen converts a header value to a payload size. The bounds are checked in
+// uint64 so that header values near the top of the range cannot wrap past them.
+func (f LengthPrefix) payloadLen(v uint64) (int, error) {
+	var sub uint64 // Amount subtracted from v
+	if f.IncludesHeader {
+		sub = uint64(f.Width)
+	}
+	if f.Adjust >= 0 {
+		if v > math.MaxUint64-uint64(f.Adjust) {
+			return 0, ErrFrameTooLarge
+		}
+		v += uint64(f.Adjust)
+	} else {
+		sub += uint64(-(f.Adjust + 1)) + 1
+	}
+	if v < sub {
+		return 0, ErrFrameLength
+	}
+	v -= sub
+	if v > math.MaxInt {
+		return 0, ErrFrameTooLarge
+	}
+	if m := f.limit(); m >= 0 && v > uint64(m) {
+		return 0, ErrFrameTooLarge
+	}
+	return int(v), nil
+}
+
+// headerValue converts a payload size to a header value. Like payloadLen, it
+// computes in uint64 so that extreme Adjust values cannot wrap past the checks.
+func (f LengthPrefix) headerValue(n int) (uint64, error) {
+	if m := f.limit(); m >= 0 && n > m {
+		return 0, ErrFrameTooLarge
+	}
+	if n < 0 {
+		return 0, ErrFrameLength
+	}
+	var add, sub uint64 // Amounts added to and subtracted from n
+	if f.IncludesHeader {
+		add = uint64(f.Width)
+	}
+	if f.Adjust >= 0 {
+		sub = uint64(f.Adjust)
+	} else {
+		add += uint64(-(f.Adjust + 1)) + 1
+	}
+	v := uint64(n)
+	if v > math.MaxUint64-add {
+		return 0, ErrFrameTooLarge
+	}
+	v += add
+	if v < sub {
+		return 0, ErrFrameLength
+	}
+	v -= sub
+	if f.Width < 8 && v >= 1<<(8*f.Width) {
+		return 0, ErrFrameTooLarge
+	}
+	return v, nil
+}
+
+// LengthReader reads length-prefixed frames from an io.Reader.
+// Frames are reassembled from arbitrarily chunked reads using io.ReadFull.
+type LengthReader struct {
+	R      io.Reader    // The underlying reader to read frames from
+	Prefix LengthPrefix // Length header layout
+	N      int64        // Number of bytes read, including headers
+	Err    error        // First error encountered while reading
+	frame  []byte
+	cur    io.LimitedReader
+}
+
+// NewLengthReader creates a new length-prefixed frame reader that reads from the provided io.Reader.
+func NewLengthReader(r io.Reader, f LengthPrefix) *LengthReader {
+	return &LengthReader{R: r, Prefix: f}
+}
+
+// pull reads the provided byte slice from the underlying reader using io.ReadFull.
+func (l *LengthReader) pull(p []byte) {
+	if l.Err != nil {
+		return
+	}
+	n, err := io.ReadFull(l.R, p)
+	l.N += int64(n)
+	if err != nil {
+		l.Err = err
+	}
+}
+
+// next discards any unread part of the previous frame and reads the next header.
+func (l *LengthReader) next() int {
+	if l.Err != nil {
+		return 0
+	}
+	if l.cur.N > 0 {
+		n, err := io.Copy(io.Discard, &l.cur)
+		l.N += n
+		if err == nil && l.cur.N > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			l.Err = err
+			return 0
+		}
+	}
+	f := l.Prefix
+	if f.Width < 1 || f.Width > 8 {
+		l.Err = ErrHeaderWidth
+		return 0
+	}
+	var b [8]byte
+	l.pull(b[:f.Width])
+	if l.Err != nil {
+		return 0
+	}
+	var v uint64
+	for i, c := range b[:f.Width] {
+		if f.BigEndian {
+			v = v<<8 | uint64(c)
+		} else {
+			v |= uint64(c) << (8 * i)
+		}
+	}
+	n, err := f.payloadLen(v)
+	if err != nil {
+		l.Err = err
+		return 0
+	}
+	return n
+}
+
+// ReadFrame reads the next frame and returns its payload.
+// The returned slice is only valid until the next call to ReadFrame.
+func (l *LengthReader) ReadFrame() ([]byte, error) {
+	n := l.next()
+	if l.Err != nil {
+		return nil, l.Err
+	}
+	if cap(l.frame) < n {
+		l.frame = make([]byte, n)
+	}
+	l.frame = l.frame[:n]
+	l.pull(l.frame)
+	if l.Err == io.EOF {
+		l.Err = io.ErrUnexpectedEOF
+	}
+	if l.Err != nil {
+		return nil, l.Err
+	}
+	return l.frame, nil
+}
+
+// limited returns a reader bounded to the payload of the next frame.
+// Any part of the payload left unread is discarded by the following call.
+func (l *LengthReader) limited() io.Reader {
+	n := l.next()
+	if l.Err != nil {
+		return &errReader{l.Err}
+	}
+	l.cur = io.LimitedReader{R: l.R, N: int64(n)}
+	return &countReader{r: &l.cur, n: &l.N}
+}
+
+// NextDecLE returns a little-endian decoder bounded to the payload of the next frame.
+// Reading past the end of the payload sets the decoder's Err to io.ErrUnexpectedEOF or io.EOF.
+func (l *LengthReader) NextDecLE() *DecLE { return NewDecLE(l.limited()) }
+
+// NextDecBE returns a big-endian decoder bounded to the payload of the next frame.
+// Reading past the end of the payload sets the decoder's Err to io.ErrUnexpectedEOF or io.EOF.
+func (l *LengthReader) NextDecBE() *DecBE { return NewDecBE(l.limited()) }
+
+// countReader adds the number of bytes read to n.
+type countReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// errReader is an io.Reader that always returns err.
+type errReader struct{ err error }
+
+func (e *errReader) Read([]byte) (int, error) { return 0, e.err }
+
+// LengthWriter writes length-prefixed frames to an io.Writer.
+// Each frame's header and payload are written with a single call to the underlying writer.
+type LengthWriter struct {
+	W      io.Writer    // The underlying writer to write frames to
+	Prefix LengthPrefix // Length header layout
+	N      int64        // Number of bytes written, including headers
+	Err    error        // First error encountered while writing
+	buf    []byte
+}
+
+// NewLengthWriter creates a new length-prefixed frame writer that writes to the provided io.Writer.
+func NewLengthWriter(w io.Writer, f LengthPrefix) *LengthWriter {
+	return &LengthWriter{W: w, Prefix: f}
+}
+
+// WriteFrame writes the length header for p followed by p.
+func (l *LengthWriter) WriteFrame(p []byte) error {
+	if l.Err != nil {
+		return l.Err
+	}
+	f := l.Prefix
+	if f.Width < 1 || f.Width > 8 {
+		l.Err = ErrHeaderWidth
+		return l.Err
+	}
+	v, err := f.headerValue(len(p))
+	if err != nil {
+		l.Err = err
+		return err
+	}
+	l.buf = l.buf[:0]
+	for i := 0; i < f.Width; i++ {
+		shift := 8 * i
+		if f.BigEndian {
+			shift = 8 * (f.Width - 1 - i)
+		}
+		l.buf = append(l.buf, byte(v>>shift))
+	}
+	l.buf = append(l.buf, p...)
+	n, err := l.W.Write(l.buf)
+	l.N += int64(n)
+	if err == nil && n < len(l.buf) {
+		err = io.ErrShortWrite
+	}
+	l.Err = err
+	return err
+}
+
+// Write implements io.Writer by writing p as a single frame.
+func (l *LengthWriter) Write(p []byte) (int, error) {
+	if err := l.WriteFrame(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
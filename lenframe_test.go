@@ -0,0 +1,162 @@
+package bitflux
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLengthWriterHeaders(t *testing.T) {
+	payload := []byte{0xAA, 0xBB, 0xCC}
+	tests := []struct {
+		name   string
+		prefix LengthPrefix
+		want   []byte
+	}{
+		{"u8", LengthPrefix{Width: 1}, []byte{0x03}},
+		{"u16 le", LengthPrefix{Width: 2}, []byte{0x03, 0x00}},
+		{"u16 be", LengthPrefix{Width: 2, BigEndian: true}, []byte{0x00, 0x03}},
+		{"u24 be", LengthPrefix{Width: 3, BigEndian: true}, []byte{0x00, 0x00, 0x03}},
+		{"u32 includes header", LengthPrefix{Width: 4, IncludesHeader: true}, []byte{0x07, 0x00, 0x00, 0x00}},
+		{"u16 be adjust", LengthPrefix{Width: 2, BigEndian: true, Adjust: 2}, []byte{0x00, 0x01}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewLengthWriter(&buf, tt.prefix)
+			if err := w.WriteFrame(payload); err != nil {
+				t.Fatalf("WriteFrame error: %v", err)
+			}
+			want := append(tt.want, payload...)
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Fatalf("got=% x, want=% x", buf.Bytes(), want)
+			}
+
+			r := NewLengthReader(&buf, tt.prefix)
+			got, err := r.ReadFrame()
+			if err != nil {
+				t.Fatalf("ReadFrame error: %v", err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Fatalf("ReadFrame: got=% x, want=% x", got, payload)
+			}
+		})
+	}
+}
+
+func TestLengthReaderChunked(t *testing.T) {
+	prefix := LengthPrefix{Width: 2, BigEndian: true}
+	frames := [][]byte{{0x01}, {}, bytes.Repeat([]byte{0x5A}, 300)}
+	var buf bytes.Buffer
+	w := NewLengthWriter(&buf, prefix)
+	for _, f := range frames {
+		w.WriteFrame(f)
+	}
+	total := int64(buf.Len())
+
+	r := NewLengthReader(iotest.OneByteReader(&buf), prefix)
+	for i, want := range frames {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("frame %d: got %d bytes, want %d", i, len(got), len(want))
+		}
+	}
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if r.N != total {
+		t.Errorf("N: got=%d, want=%d", r.N, total)
+	}
+}
+
+func TestLengthReaderNextDec(t *testing.T) {
+	prefix := LengthPrefix{Width: 1}
+	stream := []byte{
+		0x04, 0x12, 0x34, 0x56, 0x78,
+		0x02, 0xAB, 0xCD,
+	}
+	r := NewLengthReader(bytes.NewReader(stream), prefix)
+
+	// Only part of the first payload is consumed; the rest must be skipped.
+	dec := r.NextDecBE()
+	if v := dec.U16(); v != 0x1234 {
+		t.Fatalf("U16: got=%#x, want=0x1234", v)
+	}
+
+	dec = r.NextDecBE()
+	if v := dec.U16(); v != 0xABCD {
+		t.Fatalf("U16: got=%#x, want=0xabcd", v)
+	}
+	dec.U8()
+	if dec.Err != io.EOF {
+		t.Fatalf("expected io.EOF past end of frame, got %v", dec.Err)
+	}
+	if r.N != int64(len(stream)) {
+		t.Errorf("N: got=%d, want=%d", r.N, len(stream))
+	}
+}
+
+func TestLengthFrameErrors(t *testing.T) {
+	r := NewLengthReader(bytes.NewReader([]byte{0x00, 0x10}), LengthPrefix{Width: 2, BigEndian: true, MaxFrame: 8})
+	if _, err := r.ReadFrame(); err != ErrFrameTooLarge {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+
+	r = NewLengthReader(bytes.NewReader([]byte{0x01}), LengthPrefix{Width: 1, IncludesHeader: true, Adjust: -1})
+	if _, err := r.ReadFrame(); err != ErrFrameLength {
+		t.Errorf("expected ErrFrameLength, got %v", err)
+	}
+
+	huge := []byte{0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	r = NewLengthReader(bytes.NewReader(huge), LengthPrefix{Width: 8, BigEndian: true, Adjust: 1, MaxFrame: -1})
+	if _, err := r.ReadFrame(); err != ErrFrameTooLarge {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+
+	r = NewLengthReader(bytes.NewReader(huge), LengthPrefix{Width: 8, BigEndian: true, Adjust: math.MaxInt})
+	if _, err := r.ReadFrame(); err != ErrFrameTooLarge {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+
+	r = NewLengthReader(bytes.NewReader([]byte{0x00}), LengthPrefix{Width: 1, Adjust: math.MinInt})
+	if _, err := r.ReadFrame(); err != ErrFrameLength {
+		t.Errorf("expected ErrFrameLength, got %v", err)
+	}
+
+	r = NewLengthReader(bytes.NewReader([]byte{0x05, 0x01}), LengthPrefix{Width: 1})
+	if _, err := r.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	w := NewLengthWriter(io.Discard, LengthPrefix{Width: 1})
+	if err := w.WriteFrame(make([]byte, 256)); err != ErrFrameTooLarge {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+
+	w = NewLengthWriter(io.Discard, LengthPrefix{Width: 9})
+	if err := w.WriteFrame(nil); err != ErrHeaderWidth {
+		t.Errorf("expected ErrHeaderWidth, got %v", err)
+	}
+
+	// Extreme adjustments are computed without overflow in both directions.
+	extreme := LengthPrefix{Width: 8, BigEndian: true, Adjust: math.MinInt}
+	var buf bytes.Buffer
+	if err := NewLengthWriter(&buf, extreme).WriteFrame([]byte{0xAA}); err != nil {
+		t.Fatalf("WriteFrame with Adjust MinInt: %v", err)
+	}
+	if want := []byte{0x80, 0, 0, 0, 0, 0, 0, 0x01, 0xAA}; !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("got=% x, want=% x", buf.Bytes(), want)
+	}
+	if got, err := NewLengthReader(&buf, extreme).ReadFrame(); err != nil || !bytes.Equal(got, []byte{0xAA}) {
+		t.Fatalf("ReadFrame with Adjust MinInt: got=% x err=%v", got, err)
+	}
+	w = NewLengthWriter(io.Discard, LengthPrefix{Width: 8, Adjust: math.MaxInt})
+	if err := w.WriteFrame(nil); err != ErrFrameLength {
+		t.Errorf("expected ErrFrameLength, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package bitflux
+
+import (
+	"errors"
+	"io"
+)
+
+// DefaultMaxMark is the maximum number of bytes a decoder buffers between Mark
+// and Commit when no explicit limit is configured.
+const DefaultMaxMark = 64 * 1024
+
+var (
+	// ErrMarkLimit is reported when a read after Mark would buffer more than MaxMark bytes.
+	ErrMarkLimit = errors.New("bitflux: mark buffer limit exceeded")
+	// ErrNoMark is reported by Rewind when there is no mark to return to.
+	ErrNoMark = errors.New("bitflux: rewind without mark")
+)
+
+// maxMark returns the buffering limit for a decoder's MaxMark field.
+func maxMark(n int) int {
+	if n == 0 {
+		return DefaultMaxMark
+	}
+	return n
+}
+
+// mark starts a mark at the current position of r, using seeking if r supports it.
+func (l *lookahead) mark(r io.Reader, n, base int64, limit int) {
+	l.marking = true
+	l.kept = l.kept[:0]
+	l.limit = limit
+	l.markN, l.markBase = n, base
+	l.seekPos = -1
+	if s, ok := r.(io.Seeker); ok {
+		if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+			l.seekPos = pos - int64(len(l.ahead))
+		}
+	}
+}
+
+// buffering reports whether consumed bytes must be kept for Rewind.
+func (l *lookahead) buffering() bool { return l.marking && l.seekPos < 0 }
+
+// room reports whether n more bytes can be consumed without exceeding the limit.
+func (l *lookahead) room(n int) bool {
+	return !l.buffering() || l.limit < 0 || len(l.kept)+n <= l.limit
+}
+
+// keep records consumed bytes for Rewind.
+func (l *lookahead) keep(p []byte) {
+	if l.buffering() {
+		l.kept = append(l.kept, p...)
+	}
+}
+
+// rewind returns r to the mark. Kept bytes are put back in front of the unconsumed ones.
+func (l *lookahead) rewind(r io.Reader) error {
+	if !l.marking {
+		return ErrNoMark
+	}
+	if l.seekPos >= 0 {
+		if _, err := r.(io.Seeker).Seek(l.seekPos, io.SeekStart); err != nil {
+			return err
+		}
+		l.ahead = l.ahead[:0]
+		return nil
+	}
+	// The kept bytes become the unconsumed ones and the old storage keeps the next ones.
+	ahead := append(l.kept, l.ahead...)
+	l.kept = l.buf[:0]
+	l.ahead, l.buf = ahead, ahead[:cap(ahead)]
+	return nil
+}
+
+// commit ends the mark.
+func (l *lookahead) commit() {
+	l.marking = false
+	l.kept = l.kept[:0]
+}
+
+// keepWriter keeps the bytes written to it for Rewind, and gives up the mark
+// once the limit is exceeded.
+type keepWriter struct{ l *lookahead }
+
+func (w keepWriter) Write(p []byte) (int, error) {
+	if !w.l.room(len(p)) {
+		w.l.commit()
+		return 0, ErrMarkLimit
+	}
+	w.l.keep(p)
+	return len(p), nil
+}
@@ -0,0 +1,126 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// onlyReader hides every method but Read, so the decoder has to buffer.
+type onlyReader struct{ r io.Reader }
+
+func (o onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
+
+func TestMarkRewind(t *testing.T) {
+	data := []byte{0x00, 0x02, 0xAB, 0xCD, 0x01, 0x02, 0x03}
+	for name, r := range map[string]func() io.Reader{
+		"Buffered": func() io.Reader { return iotest.OneByteReader(bytes.NewReader(data)) },
+		"Seeker":   func() io.Reader { return bytes.NewReader(data) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecBE(r())
+			dec.Mark()
+
+			// First candidate: a 32-bit magic that does not match.
+			if dec.U32() == 0xCAFEBABE {
+				t.Fatal("unexpected magic")
+			}
+			dec.Err = errors.New("not this layout")
+			dec.Rewind()
+			if dec.Err != nil || dec.N != 0 {
+				t.Fatalf("after Rewind: err=%v N=%d", dec.Err, dec.N)
+			}
+
+			// Second candidate: too long for the input.
+			dec.Skip(8)
+			if !errors.Is(dec.Err, io.ErrUnexpectedEOF) {
+				t.Fatalf("expected ErrUnexpectedEOF, got %v", dec.Err)
+			}
+			dec.Rewind()
+
+			// Third candidate: a length-prefixed payload.
+			if n := dec.U16(); n != 2 {
+				t.Fatalf("length: got=%d", n)
+			}
+			if v := dec.U16(); v != 0xABCD {
+				t.Fatalf("payload: got=%#x", v)
+			}
+			dec.Commit()
+			if rest := dec.ReadAll(); !bytes.Equal(rest, []byte{1, 2, 3}) || dec.N != 7 || dec.Err != nil {
+				t.Fatalf("ReadAll: got=% x N=%d err=%v", rest, dec.N, dec.Err)
+			}
+		})
+	}
+}
+
+func TestMarkSeekerDoesNotBuffer(t *testing.T) {
+	dec := NewDecLE(bytes.NewReader(make([]byte, 100)))
+	dec.MaxMark = 1
+	dec.Mark()
+	dec.Skip(100)
+	if dec.Err != nil || len(dec.kept) != 0 {
+		t.Fatalf("err=%v kept=%d", dec.Err, len(dec.kept))
+	}
+	dec.Rewind()
+	if dec.Skip(100); dec.Err != nil || dec.N != 100 {
+		t.Fatalf("second pass: err=%v N=%d", dec.Err, dec.N)
+	}
+}
+
+func TestMarkLimit(t *testing.T) {
+	dec := NewDecLE(onlyReader{bytes.NewReader([]byte{1, 0, 0, 0, 2, 3})})
+	dec.MaxMark = 4
+	dec.Mark()
+	dec.U32()
+	if dec.U8(); !errors.Is(dec.Err, ErrMarkLimit) || dec.N != 4 {
+		t.Fatalf("got err=%v N=%d, want ErrMarkLimit after 4 bytes", dec.Err, dec.N)
+	}
+	dec.Rewind()
+	if v := dec.U32(); v != 1 || dec.Err != nil {
+		t.Fatalf("after Rewind: got=%d err=%v", v, dec.Err)
+	}
+	dec.Commit()
+	if v := dec.U16(); v != 0x0302 || dec.Err != nil {
+		t.Fatalf("after Commit: got=%#x err=%v", v, dec.Err)
+	}
+
+	dec = NewDecLE(onlyReader{bytes.NewReader(make([]byte, 10))})
+	dec.MaxMark = 4
+	dec.Mark()
+	dec.ReadAll()
+	if !errors.Is(dec.Err, ErrMarkLimit) {
+		t.Fatalf("ReadAll: got err=%v, want ErrMarkLimit", dec.Err)
+	}
+	if dec.Rewind(); !errors.Is(dec.Err, ErrNoMark) {
+		t.Fatalf("Rewind after lost mark: got err=%v, want ErrNoMark", dec.Err)
+	}
+}
+
+func TestMarkPeek(t *testing.T) {
+	dec := NewDecBE(onlyReader{bytes.NewReader([]byte{1, 2, 3, 4})})
+	dec.U8()
+	dec.Peek(2)
+	dec.Mark()
+	dec.U16()
+	dec.Peek(1)
+	dec.Rewind()
+	if v := dec.U16(); v != 0x0203 || dec.N != 3 {
+		t.Fatalf("got=%#x N=%d", v, dec.N)
+	}
+	dec.Rewind()
+	if v := dec.U8(); v != 2 {
+		t.Fatalf("second Rewind: got=%d", v)
+	}
+	if v := dec.U16(); v != 0x0304 || dec.Err != nil {
+		t.Fatalf("got=%#x err=%v", v, dec.Err)
+	}
+}
+
+func TestRewindWithoutMark(t *testing.T) {
+	dec := NewDecLE(bytes.NewReader(nil))
+	if dec.Rewind(); !errors.Is(dec.Err, ErrNoMark) {
+		t.Fatalf("got err=%v, want ErrNoMark", dec.Err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package modbus encodes and decodes Modbus application protocol messages and
+// their TCP (MBAP) and serial RTU framings with bitflux big-endian encoders and
+// decoders.
+//
+// Request and response types encode and decode a whole PDU, function code
+// included, and validate their fields against the limits of the Modbus
+// application protocol specification:
+//
+//	pdu, err := modbus.MarshalPDU(&modbus.ReadRequest{Function: modbus.ReadHoldingRegisters, Address: 0x6B, Quantity: 3})
+//	frame := modbus.TCPFrame{Transaction: 1, Unit: 0x11, PDU: pdu}
+//	enc := bitflux.NewEncBE(conn)
+//	frame.EncodeBE(enc)
+package modbus
+
+import (
+	"errors"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// FunctionCode identifies the operation of a PDU.
+type FunctionCode uint8
+
+// Supported function codes.
+const (
+	ReadCoils              FunctionCode = 0x01
+	ReadDiscreteInputs     FunctionCode = 0x02
+	ReadHoldingRegisters   FunctionCode = 0x03
+	ReadInputRegisters     FunctionCode = 0x04
+	WriteSingleCoil        FunctionCode = 0x05
+	WriteSingleRegister    FunctionCode = 0x06
+	WriteMultipleCoils     FunctionCode = 0x0F
+	WriteMultipleRegisters FunctionCode = 0x10
+)
+
+var functionCodes = bitflux.NewEnum("FunctionCode", 1, map[FunctionCode]string{
+	ReadCoils:              "ReadCoils",
+	ReadDiscreteInputs:     "ReadDiscreteInputs",
+	ReadHoldingRegisters:   "ReadHoldingRegisters",
+	ReadInputRegisters:     "ReadInputRegisters",
+	WriteSingleCoil:        "WriteSingleCoil",
+	WriteSingleRegister:    "WriteSingleRegister",
+	WriteMultipleCoils:     "WriteMultipleCoils",
+	WriteMultipleRegisters: "WriteMultipleRegisters",
+})
+
+func (f FunctionCode) String() string { return functionCodes.String(f) }
+
+// ExceptionCode is the reason given in an exception response.
+type ExceptionCode uint8
+
+// Exception codes defined by the specification.
+const (
+	IllegalFunction                    ExceptionCode = 0x01
+	IllegalDataAddress                 ExceptionCode = 0x02
+	IllegalDataValue                   ExceptionCode = 0x03
+	ServerDeviceFailure                ExceptionCode = 0x04
+	Acknowledge                        ExceptionCode = 0x05
+	ServerDeviceBusy                   ExceptionCode = 0x06
+	MemoryParityError                  ExceptionCode = 0x08
+	GatewayPathUnavailable             ExceptionCode = 0x0A
+	GatewayTargetDeviceFailedToRespond ExceptionCode = 0x0B
+)
+
+var exceptionCodes = bitflux.NewEnum("ExceptionCode", 1, map[ExceptionCode]string{
+	IllegalFunction:                    "IllegalFunction",
+	IllegalDataAddress:                 "IllegalDataAddress",
+	IllegalDataValue:                   "IllegalDataValue",
+	ServerDeviceFailure:                "ServerDeviceFailure",
+	Acknowledge:                        "Acknowledge",
+	ServerDeviceBusy:                   "ServerDeviceBusy",
+	MemoryParityError:                  "MemoryParityError",
+	GatewayPathUnavailable:             "GatewayPathUnavailable",
+	GatewayTargetDeviceFailedToRespond: "GatewayTargetDeviceFailedToRespond",
+})
+
+func (c ExceptionCode) String() string { return exceptionCodes.String(c) }
+
+// Limits of the Modbus application protocol specification.
+const (
+	MaxPDU            = 253  // Maximum PDU size in bytes, function code included
+	MaxReadBits       = 2000 // Maximum quantity of coils or discrete inputs per read
+	MaxReadRegisters  = 125  // Maximum quantity of registers per read
+	MaxWriteBits      = 1968 // Maximum quantity of coils per write
+	MaxWriteRegisters = 123  // Maximum quantity of registers per write
+)
+
+var (
+	// ErrFunction is reported for a function code that is unsupported or not valid for the message.
+	ErrFunction = errors.New("modbus: invalid function code")
+	// ErrQuantity is reported for a quantity of coils or registers outside the specification's limits.
+	ErrQuantity = errors.New("modbus: quantity out of range")
+	// ErrAddress is reported when a range of coils or registers extends past address 0xFFFF.
+	ErrAddress = errors.New("modbus: address range out of bounds")
+	// ErrValue is reported for a malformed field, such as a byte count that does not match its quantity.
+	ErrValue = errors.New("modbus: invalid value")
+	// ErrLength is reported for a PDU or frame whose length is outside the specification's limits.
+	ErrLength = errors.New("modbus: invalid length")
+	// ErrProtocol is reported for an MBAP header whose protocol identifier is not 0.
+	ErrProtocol = errors.New("modbus: invalid MBAP protocol identifier")
+	// ErrCRC is reported for an RTU frame whose CRC does not match its contents.
+	ErrCRC = errors.New("modbus: CRC mismatch")
+)
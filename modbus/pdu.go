@@ -0,0 +1,523 @@
+package modbus
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// checkRange validates a quantity of coils or registers starting at addr.
+func checkRange(addr, qty uint16, limit int) error {
+	if qty < 1 || int(qty) > limit {
+		return fmt.Errorf("%w: %d not in 1..%d", ErrQuantity, qty, limit)
+	}
+	if int(addr)+int(qty) > 0x10000 {
+		return fmt.Errorf("%w: %d from address %d", ErrAddress, qty, addr)
+	}
+	return nil
+}
+
+// checkFunction validates that f is one of want.
+func checkFunction(f FunctionCode, want ...FunctionCode) error {
+	for _, w := range want {
+		if f == w {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrFunction, f)
+}
+
+// packBits packs values into bytes, least significant bit first.
+func packBits(values []bool) []byte {
+	b := make([]byte, (len(values)+7)/8)
+	for i, v := range values {
+		if v {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+// unpackBits unpacks n values from bytes, least significant bit first.
+func unpackBits(b []byte, n int) []bool {
+	values := make([]bool, n)
+	for i := range values {
+		values[i] = b[i/8]&(1<<(i%8)) != 0
+	}
+	return values
+}
+
+// ReadRequest reads Quantity coils, discrete inputs, holding registers or input
+// registers starting at Address.
+type ReadRequest struct {
+	Function FunctionCode // ReadCoils, ReadDiscreteInputs, ReadHoldingRegisters or ReadInputRegisters
+	Address  uint16
+	Quantity uint16
+}
+
+// Validate checks the request against the specification's limits.
+func (r *ReadRequest) Validate() error {
+	switch r.Function {
+	case ReadCoils, ReadDiscreteInputs:
+		return checkRange(r.Address, r.Quantity, MaxReadBits)
+	case ReadHoldingRegisters, ReadInputRegisters:
+		return checkRange(r.Address, r.Quantity, MaxReadRegisters)
+	}
+	return fmt.Errorf("%w: %v", ErrFunction, r.Function)
+}
+
+// EncodeBE writes the request PDU to e. Invalid requests set e.Err and write nothing.
+func (r *ReadRequest) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	e.U8(uint8(r.Function))
+	e.U16(r.Address)
+	e.U16(r.Quantity)
+}
+
+// DecodeBE reads a request PDU from d and validates it.
+func (r *ReadRequest) DecodeBE(d *bitflux.DecBE) {
+	r.Function = FunctionCode(d.U8())
+	r.Address = d.U16()
+	r.Quantity = d.U16()
+	if d.Err == nil {
+		d.Err = r.Validate()
+	}
+}
+
+// ReadBitsResponse holds the coils or discrete inputs returned for a ReadRequest.
+// Decoded responses hold a multiple of 8 values, the bits past the requested
+// quantity being zero.
+type ReadBitsResponse struct {
+	Function FunctionCode // ReadCoils or ReadDiscreteInputs
+	Values   []bool
+}
+
+// Validate checks the response against the specification's limits.
+func (r *ReadBitsResponse) Validate() error {
+	if err := checkFunction(r.Function, ReadCoils, ReadDiscreteInputs); err != nil {
+		return err
+	}
+	if len(r.Values) < 1 || len(r.Values) > (MaxReadBits+7)/8*8 {
+		return fmt.Errorf("%w: %d bits", ErrQuantity, len(r.Values))
+	}
+	return nil
+}
+
+// EncodeBE writes the response PDU to e. Invalid responses set e.Err and write nothing.
+func (r *ReadBitsResponse) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	b := packBits(r.Values)
+	e.U8(uint8(r.Function))
+	e.U8(uint8(len(b)))
+	e.Write(b)
+}
+
+// DecodeBE reads a response PDU from d and validates it.
+func (r *ReadBitsResponse) DecodeBE(d *bitflux.DecBE) {
+	r.Function = FunctionCode(d.U8())
+	n := int(d.U8())
+	if d.Err != nil {
+		return
+	}
+	if n < 1 || n > (MaxReadBits+7)/8 {
+		d.Err = fmt.Errorf("%w: byte count %d", ErrValue, n)
+		return
+	}
+	b := d.Bytes(n)
+	if d.Err != nil {
+		return
+	}
+	r.Values = unpackBits(b, 8*n)
+	d.Err = r.Validate()
+}
+
+// ReadRegistersResponse holds the holding or input registers returned for a ReadRequest.
+type ReadRegistersResponse struct {
+	Function FunctionCode // ReadHoldingRegisters or ReadInputRegisters
+	Values   []uint16
+}
+
+// Validate checks the response against the specification's limits.
+func (r *ReadRegistersResponse) Validate() error {
+	if err := checkFunction(r.Function, ReadHoldingRegisters, ReadInputRegisters); err != nil {
+		return err
+	}
+	if len(r.Values) < 1 || len(r.Values) > MaxReadRegisters {
+		return fmt.Errorf("%w: %d registers", ErrQuantity, len(r.Values))
+	}
+	return nil
+}
+
+// EncodeBE writes the response PDU to e. Invalid responses set e.Err and write nothing.
+func (r *ReadRegistersResponse) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	e.U8(uint8(r.Function))
+	e.U8(uint8(2 * len(r.Values)))
+	e.U16s(r.Values)
+}
+
+// DecodeBE reads a response PDU from d and validates it.
+func (r *ReadRegistersResponse) DecodeBE(d *bitflux.DecBE) {
+	r.Function = FunctionCode(d.U8())
+	n := int(d.U8())
+	if d.Err != nil {
+		return
+	}
+	if n%2 != 0 || n < 2 || n > 2*MaxReadRegisters {
+		d.Err = fmt.Errorf("%w: byte count %d", ErrValue, n)
+		return
+	}
+	r.Values = make([]uint16, n/2)
+	d.U16s(r.Values)
+	if d.Err == nil {
+		d.Err = r.Validate()
+	}
+}
+
+// WriteSingleCoilRequest sets the coil at Address. The response echoes the request.
+type WriteSingleCoilRequest struct {
+	Address uint16
+	Value   bool
+}
+
+// EncodeBE writes the PDU to e.
+func (r *WriteSingleCoilRequest) EncodeBE(e *bitflux.EncBE) {
+	e.U8(uint8(WriteSingleCoil))
+	e.U16(r.Address)
+	if r.Value {
+		e.U16(0xFF00)
+	} else {
+		e.U16(0x0000)
+	}
+}
+
+// DecodeBE reads a PDU from d. Values other than 0xFF00 and 0x0000 set d.Err to ErrValue.
+func (r *WriteSingleCoilRequest) DecodeBE(d *bitflux.DecBE) {
+	f := FunctionCode(d.U8())
+	r.Address = d.U16()
+	v := d.U16()
+	if d.Err != nil {
+		return
+	}
+	if d.Err = checkFunction(f, WriteSingleCoil); d.Err != nil {
+		return
+	}
+	switch v {
+	case 0xFF00:
+		r.Value = true
+	case 0x0000:
+		r.Value = false
+	default:
+		d.Err = fmt.Errorf("%w: coil value %#04x", ErrValue, v)
+	}
+}
+
+// WriteSingleRegisterRequest sets the holding register at Address. The response echoes the request.
+type WriteSingleRegisterRequest struct {
+	Address uint16
+	Value   uint16
+}
+
+// EncodeBE writes the PDU to e.
+func (r *WriteSingleRegisterRequest) EncodeBE(e *bitflux.EncBE) {
+	e.U8(uint8(WriteSingleRegister))
+	e.U16(r.Address)
+	e.U16(r.Value)
+}
+
+// DecodeBE reads a PDU from d.
+func (r *WriteSingleRegisterRequest) DecodeBE(d *bitflux.DecBE) {
+	f := FunctionCode(d.U8())
+	r.Address = d.U16()
+	r.Value = d.U16()
+	if d.Err == nil {
+		d.Err = checkFunction(f, WriteSingleRegister)
+	}
+}
+
+// WriteMultipleCoilsRequest sets consecutive coils starting at Address.
+type WriteMultipleCoilsRequest struct {
+	Address uint16
+	Values  []bool
+}
+
+// Validate checks the request against the specification's limits.
+func (r *WriteMultipleCoilsRequest) Validate() error {
+	if len(r.Values) > MaxWriteBits {
+		return fmt.Errorf("%w: %d not in 1..%d", ErrQuantity, len(r.Values), MaxWriteBits)
+	}
+	return checkRange(r.Address, uint16(len(r.Values)), MaxWriteBits)
+}
+
+// EncodeBE writes the request PDU to e. Invalid requests set e.Err and write nothing.
+func (r *WriteMultipleCoilsRequest) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	b := packBits(r.Values)
+	e.U8(uint8(WriteMultipleCoils))
+	e.U16(r.Address)
+	e.U16(uint16(len(r.Values)))
+	e.U8(uint8(len(b)))
+	e.Write(b)
+}
+
+// DecodeBE reads a request PDU from d and validates it.
+func (r *WriteMultipleCoilsRequest) DecodeBE(d *bitflux.DecBE) {
+	f := FunctionCode(d.U8())
+	r.Address = d.U16()
+	qty := d.U16()
+	n := int(d.U8())
+	if d.Err != nil {
+		return
+	}
+	if d.Err = checkFunction(f, WriteMultipleCoils); d.Err != nil {
+		return
+	}
+	if d.Err = checkRange(r.Address, qty, MaxWriteBits); d.Err != nil {
+		return
+	}
+	if n != (int(qty)+7)/8 {
+		d.Err = fmt.Errorf("%w: byte count %d for %d coils", ErrValue, n, qty)
+		return
+	}
+	b := d.Bytes(n)
+	if d.Err == nil {
+		r.Values = unpackBits(b, int(qty))
+	}
+}
+
+// WriteMultipleRegistersRequest sets consecutive holding registers starting at Address.
+type WriteMultipleRegistersRequest struct {
+	Address uint16
+	Values  []uint16
+}
+
+// Validate checks the request against the specification's limits.
+func (r *WriteMultipleRegistersRequest) Validate() error {
+	if len(r.Values) > MaxWriteRegisters {
+		return fmt.Errorf("%w: %d not in 1..%d", ErrQuantity, len(r.Values), MaxWriteRegisters)
+	}
+	return checkRange(r.Address, uint16(len(r.Values)), MaxWriteRegisters)
+}
+
+// EncodeBE writes the request PDU to e. Invalid requests set e.Err and write nothing.
+func (r *WriteMultipleRegistersRequest) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	e.U8(uint8(WriteMultipleRegisters))
+	e.U16(r.Address)
+	e.U16(uint16(len(r.Values)))
+	e.U8(uint8(2 * len(r.Values)))
+	e.U16s(r.Values)
+}
+
+// DecodeBE reads a request PDU from d and validates it.
+func (r *WriteMultipleRegistersRequest) DecodeBE(d *bitflux.DecBE) {
+	f := FunctionCode(d.U8())
+	r.Address = d.U16()
+	qty := d.U16()
+	n := int(d.U8())
+	if d.Err != nil {
+		return
+	}
+	if d.Err = checkFunction(f, WriteMultipleRegisters); d.Err != nil {
+		return
+	}
+	if d.Err = checkRange(r.Address, qty, MaxWriteRegisters); d.Err != nil {
+		return
+	}
+	if n != 2*int(qty) {
+		d.Err = fmt.Errorf("%w: byte count %d for %d registers", ErrValue, n, qty)
+		return
+	}
+	r.Values = make([]uint16, qty)
+	d.U16s(r.Values)
+}
+
+// WriteMultipleResponse acknowledges a WriteMultipleCoilsRequest or WriteMultipleRegistersRequest.
+type WriteMultipleResponse struct {
+	Function FunctionCode // WriteMultipleCoils or WriteMultipleRegisters
+	Address  uint16
+	Quantity uint16
+}
+
+// Validate checks the response against the specification's limits.
+func (r *WriteMultipleResponse) Validate() error {
+	switch r.Function {
+	case WriteMultipleCoils:
+		return checkRange(r.Address, r.Quantity, MaxWriteBits)
+	case WriteMultipleRegisters:
+		return checkRange(r.Address, r.Quantity, MaxWriteRegisters)
+	}
+	return fmt.Errorf("%w: %v", ErrFunction, r.Function)
+}
+
+// EncodeBE writes the response PDU to e. Invalid responses set e.Err and write nothing.
+func (r *WriteMultipleResponse) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if e.Err = r.Validate(); e.Err != nil {
+		return
+	}
+	e.U8(uint8(r.Function))
+	e.U16(r.Address)
+	e.U16(r.Quantity)
+}
+
+// DecodeBE reads a response PDU from d and validates it.
+func (r *WriteMultipleResponse) DecodeBE(d *bitflux.DecBE) {
+	r.Function = FunctionCode(d.U8())
+	r.Address = d.U16()
+	r.Quantity = d.U16()
+	if d.Err == nil {
+		d.Err = r.Validate()
+	}
+}
+
+// Exception is the response of a server that could not perform a request.
+// It implements error.
+type Exception struct {
+	Function FunctionCode // Function code of the failed request
+	Code     ExceptionCode
+}
+
+func (x *Exception) Error() string {
+	return fmt.Sprintf("modbus: exception %v for %v", x.Code, x.Function)
+}
+
+// EncodeBE writes the exception PDU to e.
+func (x *Exception) EncodeBE(e *bitflux.EncBE) {
+	e.U8(uint8(x.Function) | 0x80)
+	e.U8(uint8(x.Code))
+}
+
+// DecodeBE reads an exception PDU from d.
+func (x *Exception) DecodeBE(d *bitflux.DecBE) {
+	f := d.U8()
+	x.Code = ExceptionCode(d.U8())
+	if d.Err == nil && f&0x80 == 0 {
+		d.Err = fmt.Errorf("%w: %#02x is not an exception", ErrFunction, f)
+	}
+	x.Function = FunctionCode(f &^ 0x80)
+}
+
+// DecodeRequest reads a request PDU from d, choosing its type from the function code.
+// It returns nil and sets d.Err on failure.
+func DecodeRequest(d *bitflux.DecBE) any {
+	var m bitflux.DecoderBE
+	switch f := FunctionCode(d.PeekU8()); f {
+	case ReadCoils, ReadDiscreteInputs, ReadHoldingRegisters, ReadInputRegisters:
+		m = new(ReadRequest)
+	case WriteSingleCoil:
+		m = new(WriteSingleCoilRequest)
+	case WriteSingleRegister:
+		m = new(WriteSingleRegisterRequest)
+	case WriteMultipleCoils:
+		m = new(WriteMultipleCoilsRequest)
+	case WriteMultipleRegisters:
+		m = new(WriteMultipleRegistersRequest)
+	default:
+		if d.Err == nil {
+			d.Err = fmt.Errorf("%w: %v", ErrFunction, f)
+		}
+		return nil
+	}
+	m.DecodeBE(d)
+	if d.Err != nil {
+		return nil
+	}
+	return m
+}
+
+// DecodeResponse reads a response PDU from d, choosing its type from the function code.
+// Exception responses are returned as *Exception. It returns nil and sets d.Err on failure.
+func DecodeResponse(d *bitflux.DecBE) any {
+	var m bitflux.DecoderBE
+	f := FunctionCode(d.PeekU8())
+	switch {
+	case d.Err != nil:
+		return nil
+	case f&0x80 != 0:
+		m = new(Exception)
+	case f == ReadCoils, f == ReadDiscreteInputs:
+		m = new(ReadBitsResponse)
+	case f == ReadHoldingRegisters, f == ReadInputRegisters:
+		m = new(ReadRegistersResponse)
+	case f == WriteSingleCoil:
+		m = new(WriteSingleCoilRequest)
+	case f == WriteSingleRegister:
+		m = new(WriteSingleRegisterRequest)
+	case f == WriteMultipleCoils, f == WriteMultipleRegisters:
+		m = new(WriteMultipleResponse)
+	default:
+		d.Err = fmt.Errorf("%w: %v", ErrFunction, f)
+		return nil
+	}
+	m.DecodeBE(d)
+	if d.Err != nil {
+		return nil
+	}
+	return m
+}
+
+// MarshalPDU encodes m into a new PDU and checks its length.
+func MarshalPDU(m bitflux.EncoderBE) ([]byte, error) {
+	enc := bitflux.GetEncBE()
+	defer enc.Release()
+	m.EncodeBE(&enc.EncBE)
+	if enc.Err != nil {
+		return nil, enc.Err
+	}
+	if n := len(enc.Bytes()); n < 1 || n > MaxPDU {
+		return nil, fmt.Errorf("%w: PDU of %d bytes", ErrLength, n)
+	}
+	return bytes.Clone(enc.Bytes()), nil
+}
+
+// ParseRequest decodes a request from a complete PDU, such as TCPFrame.PDU.
+func ParseRequest(pdu []byte) (any, error) {
+	return parse(pdu, DecodeRequest)
+}
+
+// ParseResponse decodes a response from a complete PDU, such as TCPFrame.PDU.
+// Exception responses are returned as *Exception with a nil error.
+func ParseResponse(pdu []byte) (any, error) {
+	return parse(pdu, DecodeResponse)
+}
+
+func parse(pdu []byte, decode func(d *bitflux.DecBE) any) (any, error) {
+	d := bitflux.NewDecBE(bytes.NewReader(pdu))
+	m := decode(d)
+	if d.Err != nil {
+		return nil, d.Err
+	}
+	if d.N != int64(len(pdu)) {
+		return nil, fmt.Errorf("%w: %d trailing bytes in PDU", ErrLength, int64(len(pdu))-d.N)
+	}
+	return m, nil
+}
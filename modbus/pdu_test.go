@@ -0,0 +1,129 @@
+package modbus
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// Examples from the Modbus application protocol specification.
+var pduTests = []struct {
+	name     string
+	pdu      []byte
+	request  bool
+	msg      any
+	trimBits int // number of decoded bit values to compare, for bit responses
+}{
+	{"ReadCoilsRequest", []byte{0x01, 0x00, 0x13, 0x00, 0x13}, true,
+		&ReadRequest{Function: ReadCoils, Address: 0x13, Quantity: 0x13}, 0},
+	{"ReadCoilsResponse", []byte{0x01, 0x03, 0xCD, 0x6B, 0x05}, false,
+		&ReadBitsResponse{Function: ReadCoils, Values: []bool{
+			true, false, true, true, false, false, true, true,
+			true, true, false, true, false, true, true, false,
+			true, false, true}}, 19},
+	{"ReadHoldingRegistersRequest", []byte{0x03, 0x00, 0x6B, 0x00, 0x03}, true,
+		&ReadRequest{Function: ReadHoldingRegisters, Address: 0x6B, Quantity: 3}, 0},
+	{"ReadHoldingRegistersResponse", []byte{0x03, 0x06, 0x02, 0x2B, 0x00, 0x00, 0x00, 0x64}, false,
+		&ReadRegistersResponse{Function: ReadHoldingRegisters, Values: []uint16{0x022B, 0x0000, 0x0064}}, 0},
+	{"WriteSingleCoil", []byte{0x05, 0x00, 0xAC, 0xFF, 0x00}, true,
+		&WriteSingleCoilRequest{Address: 0xAC, Value: true}, 0},
+	{"WriteSingleRegister", []byte{0x06, 0x00, 0x01, 0x00, 0x03}, true,
+		&WriteSingleRegisterRequest{Address: 1, Value: 3}, 0},
+	{"WriteMultipleCoilsRequest", []byte{0x0F, 0x00, 0x13, 0x00, 0x0A, 0x02, 0xCD, 0x01}, true,
+		&WriteMultipleCoilsRequest{Address: 0x13, Values: []bool{
+			true, false, true, true, false, false, true, true, true, false}}, 0},
+	{"WriteMultipleCoilsResponse", []byte{0x0F, 0x00, 0x13, 0x00, 0x0A}, false,
+		&WriteMultipleResponse{Function: WriteMultipleCoils, Address: 0x13, Quantity: 10}, 0},
+	{"WriteMultipleRegistersRequest", []byte{0x10, 0x00, 0x01, 0x00, 0x02, 0x04, 0x00, 0x0A, 0x01, 0x02}, true,
+		&WriteMultipleRegistersRequest{Address: 1, Values: []uint16{0x000A, 0x0102}}, 0},
+	{"WriteMultipleRegistersResponse", []byte{0x10, 0x00, 0x01, 0x00, 0x02}, false,
+		&WriteMultipleResponse{Function: WriteMultipleRegisters, Address: 1, Quantity: 2}, 0},
+	{"Exception", []byte{0x81, 0x02}, false,
+		&Exception{Function: ReadCoils, Code: IllegalDataAddress}, 0},
+}
+
+func TestPDU(t *testing.T) {
+	for _, tt := range pduTests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdu, err := MarshalPDU(tt.msg.(bitflux.EncoderBE))
+			if err != nil {
+				t.Fatalf("MarshalPDU: %v", err)
+			}
+			if !bytes.Equal(pdu, tt.pdu) {
+				t.Fatalf("MarshalPDU: got=% x, want=% x", pdu, tt.pdu)
+			}
+
+			parse := ParseResponse
+			if tt.request {
+				parse = ParseRequest
+			}
+			got, err := parse(tt.pdu)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if r, ok := got.(*ReadBitsResponse); ok && tt.trimBits > 0 {
+				if len(r.Values) != 8*int(tt.pdu[1]) {
+					t.Fatalf("decoded %d bits, want %d", len(r.Values), 8*int(tt.pdu[1]))
+				}
+				r.Values = r.Values[:tt.trimBits]
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Fatalf("parse: got=%+v, want=%+v", got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestPDULimits(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		msg  bitflux.EncoderBE
+		err  error
+	}{
+		{"NoRegisters", &ReadRequest{Function: ReadHoldingRegisters, Quantity: 0}, ErrQuantity},
+		{"TooManyRegisters", &ReadRequest{Function: ReadInputRegisters, Quantity: MaxReadRegisters + 1}, ErrQuantity},
+		{"TooManyCoils", &ReadRequest{Function: ReadCoils, Quantity: MaxReadBits + 1}, ErrQuantity},
+		{"PastLastAddress", &ReadRequest{Function: ReadCoils, Address: 0xFFFF, Quantity: 2}, ErrAddress},
+		{"WriteFunction", &ReadRequest{Function: WriteSingleCoil, Quantity: 1}, ErrFunction},
+		{"TooManyWriteCoils", &WriteMultipleCoilsRequest{Values: make([]bool, MaxWriteBits+1)}, ErrQuantity},
+		{"TooManyWriteRegisters", &WriteMultipleRegistersRequest{Values: make([]uint16, MaxWriteRegisters+1)}, ErrQuantity},
+		{"NoWriteRegisters", &WriteMultipleRegistersRequest{}, ErrQuantity},
+		{"ResponseFunction", &ReadRegistersResponse{Function: ReadCoils, Values: []uint16{1}}, ErrFunction},
+	} {
+		if _, err := MarshalPDU(tt.msg); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got err=%v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		pdu     []byte
+		request bool
+		err     error
+	}{
+		{"CoilValue", []byte{0x05, 0x00, 0x01, 0x12, 0x34}, true, ErrValue},
+		{"CoilByteCount", []byte{0x0F, 0x00, 0x13, 0x00, 0x0A, 0x01, 0xCD}, true, ErrValue},
+		{"RegisterByteCount", []byte{0x10, 0x00, 0x01, 0x00, 0x02, 0x03, 0x00, 0x0A, 0x01}, true, ErrValue},
+		{"OddByteCount", []byte{0x03, 0x03, 0x00, 0x00, 0x00}, false, ErrValue},
+		{"UnknownFunction", []byte{0x2B, 0x0E}, true, ErrFunction},
+		{"Trailing", []byte{0x06, 0x00, 0x01, 0x00, 0x03, 0xFF}, true, ErrLength},
+	} {
+		parse := ParseResponse
+		if tt.request {
+			parse = ParseRequest
+		}
+		if _, err := parse(tt.pdu); !errors.Is(err, tt.err) {
+			t.Errorf("%s: got err=%v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestExceptionError(t *testing.T) {
+	var err error = &Exception{Function: ReadHoldingRegisters, Code: IllegalDataAddress}
+	if got := err.Error(); got != "modbus: exception IllegalDataAddress for ReadHoldingRegisters" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,316 @@
+package modbus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// WordOrder is the layout of a value spread over several 16-bit registers. The
+// letters name the bytes of a 32-bit value from most to least significant, as
+// they appear in the registers; 64-bit values extend the same pattern.
+type WordOrder uint8
+
+// Word orders used by Modbus devices.
+const (
+	ABCD WordOrder = iota // Big-endian: most significant register first, as in the specification
+	CDAB                  // Least significant register first, bytes big-endian within each register
+	BADC                  // Most significant register first, bytes swapped within each register
+	DCBA                  // Little-endian: least significant register first, bytes swapped
+)
+
+var wordOrders = bitflux.NewEnum("WordOrder", 1, map[WordOrder]string{
+	ABCD: "ABCD",
+	CDAB: "CDAB",
+	BADC: "BADC",
+	DCBA: "DCBA",
+})
+
+func (o WordOrder) String() string { return wordOrders.String(o) }
+
+// swapsWords reports whether the least significant register comes first.
+func (o WordOrder) swapsWords() bool { return o == CDAB || o == DCBA }
+
+// swapsBytes reports whether the bytes of each register are swapped.
+func (o WordOrder) swapsBytes() bool { return o == BADC || o == DCBA }
+
+// put stores the value held in regs in b as big-endian bytes. b must hold 2*len(regs) bytes.
+func (o WordOrder) put(b []byte, regs []uint16) {
+	for i, w := range regs {
+		j := i
+		if o.swapsWords() {
+			j = len(regs) - 1 - i
+		}
+		if o.swapsBytes() {
+			w = w<<8 | w>>8
+		}
+		b[2*j], b[2*j+1] = byte(w>>8), byte(w)
+	}
+}
+
+// get stores the big-endian value in b in regs. b must hold 2*len(regs) bytes.
+func (o WordOrder) get(regs []uint16, b []byte) {
+	for i := range regs {
+		j := i
+		if o.swapsWords() {
+			j = len(regs) - 1 - i
+		}
+		w := uint16(b[2*j])<<8 | uint16(b[2*j+1])
+		if o.swapsBytes() {
+			w = w<<8 | w>>8
+		}
+		regs[i] = w
+	}
+}
+
+// RegisterDecoder reads typed values from a block of registers, such as
+// ReadRegistersResponse.Values, laid out in a device's word order:
+//
+//	rd := modbus.NewRegisterDecoder(resp.Values, modbus.CDAB)
+//	temp := rd.F32()
+//	count := rd.U32()
+//	if rd.Err != nil { ... }
+//
+// Values are reordered to big-endian and decoded with a bitflux.DecBE.
+type RegisterDecoder struct {
+	Regs  []uint16  // Registers to decode
+	Order WordOrder // Layout of values wider than one register
+	N     int       // Number of registers read
+	Err   error     // First error encountered during decoding
+
+	scratch [8]byte
+	buf     bytes.Reader
+	dec     bitflux.DecBE
+}
+
+// NewRegisterDecoder creates a decoder reading regs in the given word order.
+func NewRegisterDecoder(regs []uint16, order WordOrder) *RegisterDecoder {
+	return &RegisterDecoder{Regs: regs, Order: order}
+}
+
+// take consumes n registers and returns them, or nil and sets Err if n is negative or fewer remain.
+func (r *RegisterDecoder) take(n int) []uint16 {
+	if r.Err != nil {
+		return nil
+	}
+	if n < 0 {
+		r.Err = fmt.Errorf("%w: %d registers", ErrLength, n)
+		return nil
+	}
+	if len(r.Regs)-r.N < n {
+		r.Err = fmt.Errorf("%w: %d registers left, need %d", io.ErrUnexpectedEOF, len(r.Regs)-r.N, n)
+		return nil
+	}
+	regs := r.Regs[r.N : r.N+n]
+	r.N += n
+	return regs
+}
+
+// value consumes n registers and returns a decoder positioned on their big-endian bytes.
+func (r *RegisterDecoder) value(n int) *bitflux.DecBE {
+	regs := r.take(n)
+	if regs == nil {
+		return nil
+	}
+	b := r.scratch[:2*n]
+	r.Order.put(b, regs)
+	r.buf.Reset(b)
+	r.dec.Reset(&r.buf)
+	return &r.dec
+}
+
+// U16 reads one register. Only the byte swap of the word order applies.
+func (r *RegisterDecoder) U16() uint16 {
+	if d := r.value(1); d != nil {
+		return d.U16()
+	}
+	return 0
+}
+
+// I16 reads one register as a signed integer.
+func (r *RegisterDecoder) I16() int16 { return int16(r.U16()) }
+
+// U32 reads two registers.
+func (r *RegisterDecoder) U32() uint32 {
+	if d := r.value(2); d != nil {
+		return d.U32()
+	}
+	return 0
+}
+
+// I32 reads two registers as a signed integer.
+func (r *RegisterDecoder) I32() int32 {
+	if d := r.value(2); d != nil {
+		return d.I32()
+	}
+	return 0
+}
+
+// F32 reads two registers as an IEEE 754 single-precision float.
+func (r *RegisterDecoder) F32() float32 {
+	if d := r.value(2); d != nil {
+		return d.F32()
+	}
+	return 0
+}
+
+// U64 reads four registers.
+func (r *RegisterDecoder) U64() uint64 {
+	if d := r.value(4); d != nil {
+		return d.U64()
+	}
+	return 0
+}
+
+// I64 reads four registers as a signed integer.
+func (r *RegisterDecoder) I64() int64 {
+	if d := r.value(4); d != nil {
+		return d.I64()
+	}
+	return 0
+}
+
+// F64 reads four registers as an IEEE 754 double-precision float.
+func (r *RegisterDecoder) F64() float64 {
+	if d := r.value(4); d != nil {
+		return d.F64()
+	}
+	return 0
+}
+
+// String reads n registers holding two characters each, in register order, and
+// trims trailing NUL bytes. Only the byte swap of the word order applies.
+func (r *RegisterDecoder) String(n int) string {
+	regs := r.take(n)
+	if regs == nil {
+		return ""
+	}
+	b := make([]byte, 2*n)
+	for i := range regs {
+		r.Order.put(b[2*i:2*i+2], regs[i:i+1])
+	}
+	return string(bytes.TrimRight(b, "\x00"))
+}
+
+// RegisterEncoder appends typed values to a block of registers, such as
+// WriteMultipleRegistersRequest.Values, laid out in a device's word order.
+// Values are encoded big-endian with a bitflux.EncBE and then reordered.
+type RegisterEncoder struct {
+	Regs  []uint16  // Encoded registers
+	Order WordOrder // Layout of values wider than one register
+	Err   error     // First error encountered during encoding
+
+	buf bytes.Buffer
+	enc bitflux.EncBE
+}
+
+// NewRegisterEncoder creates an encoder appending registers in the given word order.
+func NewRegisterEncoder(order WordOrder) *RegisterEncoder {
+	return &RegisterEncoder{Order: order}
+}
+
+// value returns an encoder for the big-endian bytes of the next value, or nil after an error.
+func (e *RegisterEncoder) value() *bitflux.EncBE {
+	if e.Err != nil {
+		return nil
+	}
+	e.buf.Reset()
+	e.enc.Reset(&e.buf)
+	return &e.enc
+}
+
+// flush appends the encoded value to Regs.
+func (e *RegisterEncoder) flush() {
+	if e.Err = e.enc.Err; e.Err != nil {
+		return
+	}
+	b := e.buf.Bytes()
+	n := len(e.Regs)
+	e.Regs = append(e.Regs, make([]uint16, len(b)/2)...)
+	e.Order.get(e.Regs[n:], b)
+}
+
+// U16 appends one register. Only the byte swap of the word order applies.
+func (e *RegisterEncoder) U16(v uint16) {
+	if enc := e.value(); enc != nil {
+		enc.U16(v)
+		e.flush()
+	}
+}
+
+// I16 appends a signed integer as one register.
+func (e *RegisterEncoder) I16(v int16) { e.U16(uint16(v)) }
+
+// U32 appends two registers.
+func (e *RegisterEncoder) U32(v uint32) {
+	if enc := e.value(); enc != nil {
+		enc.U32(v)
+		e.flush()
+	}
+}
+
+// I32 appends a signed integer as two registers.
+func (e *RegisterEncoder) I32(v int32) {
+	if enc := e.value(); enc != nil {
+		enc.I32(v)
+		e.flush()
+	}
+}
+
+// F32 appends an IEEE 754 single-precision float as two registers.
+func (e *RegisterEncoder) F32(v float32) {
+	if enc := e.value(); enc != nil {
+		enc.F32(v)
+		e.flush()
+	}
+}
+
+// U64 appends four registers.
+func (e *RegisterEncoder) U64(v uint64) {
+	if enc := e.value(); enc != nil {
+		enc.U64(v)
+		e.flush()
+	}
+}
+
+// I64 appends a signed integer as four registers.
+func (e *RegisterEncoder) I64(v int64) {
+	if enc := e.value(); enc != nil {
+		enc.I64(v)
+		e.flush()
+	}
+}
+
+// F64 appends an IEEE 754 double-precision float as four registers.
+func (e *RegisterEncoder) F64(v float64) {
+	if enc := e.value(); enc != nil {
+		enc.F64(v)
+		e.flush()
+	}
+}
+
+// String appends s as n registers holding two characters each, padded with NUL
+// bytes. Only the byte swap of the word order applies. Err is set to an error
+// wrapping ErrLength if n is negative or s does not fit.
+func (e *RegisterEncoder) String(s string, n int) {
+	if e.Err != nil {
+		return
+	}
+	if n < 0 {
+		e.Err = fmt.Errorf("%w: %d registers", ErrLength, n)
+		return
+	}
+	if len(s) > 2*n {
+		e.Err = fmt.Errorf("%w: string of %d bytes in %d registers", ErrLength, len(s), n)
+		return
+	}
+	b := make([]byte, 2*n)
+	copy(b, s)
+	m := len(e.Regs)
+	e.Regs = append(e.Regs, make([]uint16, n)...)
+	for i := range n {
+		e.Order.get(e.Regs[m+i:m+i+1], b[2*i:2*i+2])
+	}
+}
@@ -0,0 +1,133 @@
+package modbus
+
+import (
+	"errors"
+	"io"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestWordOrder(t *testing.T) {
+	for _, tt := range []struct {
+		order WordOrder
+		u32   []uint16 // 0x11223344
+		f32   []uint16 // 1.0
+		u64   []uint16 // 0x1122334455667788
+	}{
+		{ABCD, []uint16{0x1122, 0x3344}, []uint16{0x3F80, 0x0000}, []uint16{0x1122, 0x3344, 0x5566, 0x7788}},
+		{CDAB, []uint16{0x3344, 0x1122}, []uint16{0x0000, 0x3F80}, []uint16{0x7788, 0x5566, 0x3344, 0x1122}},
+		{BADC, []uint16{0x2211, 0x4433}, []uint16{0x803F, 0x0000}, []uint16{0x2211, 0x4433, 0x6655, 0x8877}},
+		{DCBA, []uint16{0x4433, 0x2211}, []uint16{0x0000, 0x803F}, []uint16{0x8877, 0x6655, 0x4433, 0x2211}},
+	} {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			e := NewRegisterEncoder(tt.order)
+			e.U32(0x11223344)
+			e.F32(1)
+			e.U64(0x1122334455667788)
+			want := slices.Concat(tt.u32, tt.f32, tt.u64)
+			if e.Err != nil || !slices.Equal(e.Regs, want) {
+				t.Fatalf("encoded: got=%04x err=%v, want=%04x", e.Regs, e.Err, want)
+			}
+
+			d := NewRegisterDecoder(want, tt.order)
+			if got := d.U32(); got != 0x11223344 {
+				t.Errorf("U32: got=%#x", got)
+			}
+			if got := d.F32(); got != 1 {
+				t.Errorf("F32: got=%v", got)
+			}
+			if got := d.U64(); got != 0x1122334455667788 {
+				t.Errorf("U64: got=%#x", got)
+			}
+			if d.Err != nil || d.N != len(want) {
+				t.Errorf("N=%d err=%v", d.N, d.Err)
+			}
+		})
+	}
+}
+
+func TestRegisterRoundTrip(t *testing.T) {
+	for _, order := range []WordOrder{ABCD, CDAB, BADC, DCBA} {
+		e := NewRegisterEncoder(order)
+		e.U16(0xBEEF)
+		e.I16(-2)
+		e.I32(-123456)
+		e.F32(-1.5)
+		e.I64(math.MinInt64 + 1)
+		e.F64(math.Pi)
+		e.String("PUMP-7", 4)
+		if e.Err != nil || len(e.Regs) != 1+1+2+2+4+4+4 {
+			t.Fatalf("%v: %d registers, err=%v", order, len(e.Regs), e.Err)
+		}
+
+		d := NewRegisterDecoder(e.Regs, order)
+		if v := d.U16(); v != 0xBEEF {
+			t.Errorf("%v: U16 got=%#x", order, v)
+		}
+		if v := d.I16(); v != -2 {
+			t.Errorf("%v: I16 got=%d", order, v)
+		}
+		if v := d.I32(); v != -123456 {
+			t.Errorf("%v: I32 got=%d", order, v)
+		}
+		if v := d.F32(); v != -1.5 {
+			t.Errorf("%v: F32 got=%v", order, v)
+		}
+		if v := d.I64(); v != math.MinInt64+1 {
+			t.Errorf("%v: I64 got=%d", order, v)
+		}
+		if v := d.F64(); v != math.Pi {
+			t.Errorf("%v: F64 got=%v", order, v)
+		}
+		if v := d.String(4); v != "PUMP-7" {
+			t.Errorf("%v: String got=%q", order, v)
+		}
+		if d.Err != nil {
+			t.Errorf("%v: %v", order, d.Err)
+		}
+	}
+}
+
+func TestRegisterString(t *testing.T) {
+	// Strings keep their register order; only the bytes within registers swap.
+	for _, tt := range []struct {
+		order WordOrder
+		regs  []uint16
+	}{
+		{ABCD, []uint16{0x4142, 0x3132}},
+		{CDAB, []uint16{0x4142, 0x3132}},
+		{BADC, []uint16{0x4241, 0x3231}},
+		{DCBA, []uint16{0x4241, 0x3231}},
+	} {
+		e := NewRegisterEncoder(tt.order)
+		if e.String("AB12", 2); !slices.Equal(e.Regs, tt.regs) {
+			t.Errorf("%v: got=%04x, want=%04x", tt.order, e.Regs, tt.regs)
+		}
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	d := NewRegisterDecoder([]uint16{1, 2, 3}, ABCD)
+	d.U16()
+	if v := d.F64(); v != 0 || !errors.Is(d.Err, io.ErrUnexpectedEOF) {
+		t.Fatalf("short block: got=%v err=%v", v, d.Err)
+	}
+	if d.U16(); d.N != 1 {
+		t.Fatalf("read after error: N=%d", d.N)
+	}
+	d = NewRegisterDecoder([]uint16{1, 2, 3}, ABCD)
+	if v := d.String(-1); v != "" || !errors.Is(d.Err, ErrLength) || d.N != 0 {
+		t.Fatalf("negative string length: got=%q N=%d err=%v", v, d.N, d.Err)
+	}
+
+	e := NewRegisterEncoder(ABCD)
+	e.String("too long", 3)
+	if e.U16(1); !errors.Is(e.Err, ErrLength) || len(e.Regs) != 0 {
+		t.Fatalf("long string: regs=%04x err=%v", e.Regs, e.Err)
+	}
+	e = NewRegisterEncoder(ABCD)
+	if e.String("", -1); !errors.Is(e.Err, ErrLength) || len(e.Regs) != 0 {
+		t.Fatalf("negative string length: regs=%04x err=%v", e.Regs, e.Err)
+	}
+}
@@ -0,0 +1,80 @@
+package modbus
+
+import (
+	"fmt"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// MaxRTUFrame is the maximum size of an RTU frame: address, PDU and CRC.
+const MaxRTUFrame = 1 + MaxPDU + 2
+
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		v := uint16(i)
+		for k := 0; k < 8; k++ {
+			if v&1 != 0 {
+				v = v>>1 ^ 0xA001
+			} else {
+				v >>= 1
+			}
+		}
+		t[i] = v
+	}
+	return t
+}()
+
+// CRC16 computes the CRC-16/MODBUS checksum of p.
+func CRC16(p []byte) uint16 {
+	v := uint16(0xFFFF)
+	for _, c := range p {
+		v = v>>8 ^ crc16Table[byte(v)^c]
+	}
+	return v
+}
+
+// RTUFrame is a Modbus serial RTU frame: a device address, a PDU and a CRC.
+// RTU frames are delimited by silence on the line, so they are decoded from a
+// complete frame with UnmarshalBinary.
+type RTUFrame struct {
+	Address uint8  // Address of the target device, 0 for broadcast
+	PDU     []byte // Function code and data
+}
+
+// MarshalBinary returns the frame with its CRC appended, low byte first.
+func (f *RTUFrame) MarshalBinary() ([]byte, error) {
+	if len(f.PDU) < 1 || len(f.PDU) > MaxPDU {
+		return nil, fmt.Errorf("%w: PDU of %d bytes", ErrLength, len(f.PDU))
+	}
+	b := make([]byte, 0, len(f.PDU)+3)
+	b = append(b, f.Address)
+	b = append(b, f.PDU...)
+	return bitflux.AppendU16LE(b, CRC16(b)), nil
+}
+
+// UnmarshalBinary decodes a complete frame, checking its length and CRC.
+func (f *RTUFrame) UnmarshalBinary(b []byte) error {
+	if len(b) < 4 || len(b) > MaxRTUFrame {
+		return fmt.Errorf("%w: RTU frame of %d bytes", ErrLength, len(b))
+	}
+	n := len(b) - 2
+	if got, want := uint16(b[n])|uint16(b[n+1])<<8, CRC16(b[:n]); got != want {
+		return fmt.Errorf("%w: got %#04x, want %#04x", ErrCRC, got, want)
+	}
+	f.Address = b[0]
+	f.PDU = append(f.PDU[:0], b[1:n]...)
+	return nil
+}
+
+// EncodeBE writes the frame to e. A PDU outside 1..MaxPDU bytes sets e.Err and writes nothing.
+func (f *RTUFrame) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	b, err := f.MarshalBinary()
+	if err != nil {
+		e.Err = err
+		return
+	}
+	e.Write(b)
+}
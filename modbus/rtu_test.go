@@ -0,0 +1,39 @@
+package modbus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCRC16(t *testing.T) {
+	if got := CRC16([]byte("123456789")); got != 0x4B37 {
+		t.Errorf("check value: got=%#04x, want=0x4b37", got)
+	}
+}
+
+func TestRTUFrame(t *testing.T) {
+	// Example from the Modbus over serial line specification.
+	frame := []byte{0x11, 0x03, 0x00, 0x6B, 0x00, 0x03, 0x76, 0x87}
+	f := RTUFrame{Address: 0x11, PDU: frame[1:6]}
+	got, err := f.MarshalBinary()
+	if err != nil || !bytes.Equal(got, frame) {
+		t.Fatalf("MarshalBinary: got=% x err=%v", got, err)
+	}
+
+	var back RTUFrame
+	if err := back.UnmarshalBinary(frame); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if back.Address != 0x11 || !bytes.Equal(back.PDU, f.PDU) {
+		t.Fatalf("UnmarshalBinary: got=%+v", back)
+	}
+
+	frame[3] ^= 0x01
+	if err := back.UnmarshalBinary(frame); !errors.Is(err, ErrCRC) {
+		t.Fatalf("corrupted frame: got err=%v, want ErrCRC", err)
+	}
+	if err := back.UnmarshalBinary(frame[:3]); !errors.Is(err, ErrLength) {
+		t.Fatalf("short frame: got err=%v, want ErrLength", err)
+	}
+}
@@ -0,0 +1,55 @@
+package modbus
+
+import (
+	"fmt"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// MBAPHeaderSize is the size of the MBAP header preceding the PDU in Modbus TCP, unit identifier included.
+const MBAPHeaderSize = 7
+
+// TCPFrame is a Modbus TCP application data unit: an MBAP header followed by a PDU.
+// Frames are self-delimiting, so they can be read from and written to a stream directly.
+type TCPFrame struct {
+	Transaction uint16 // Transaction identifier, echoed in the response
+	Unit        uint8  // Unit identifier of the target device
+	PDU         []byte // Function code and data
+}
+
+// EncodeBE writes the frame to e. A PDU outside 1..MaxPDU bytes sets e.Err and writes nothing.
+func (f *TCPFrame) EncodeBE(e *bitflux.EncBE) {
+	if e.Err != nil {
+		return
+	}
+	if len(f.PDU) < 1 || len(f.PDU) > MaxPDU {
+		e.Err = fmt.Errorf("%w: PDU of %d bytes", ErrLength, len(f.PDU))
+		return
+	}
+	e.U16(f.Transaction)
+	e.U16(0) // protocol identifier
+	e.U16(uint16(1 + len(f.PDU)))
+	e.U8(f.Unit)
+	e.Write(f.PDU)
+}
+
+// DecodeBE reads a frame from d. It sets d.Err to ErrProtocol for a protocol identifier
+// other than 0 and to ErrLength for a length field outside the specification's limits.
+func (f *TCPFrame) DecodeBE(d *bitflux.DecBE) {
+	f.Transaction = d.U16()
+	protocol := d.U16()
+	n := int(d.U16())
+	if d.Err != nil {
+		return
+	}
+	if protocol != 0 {
+		d.Err = fmt.Errorf("%w: %d", ErrProtocol, protocol)
+		return
+	}
+	if n < 2 || n > 1+MaxPDU {
+		d.Err = fmt.Errorf("%w: MBAP length %d", ErrLength, n)
+		return
+	}
+	f.Unit = d.U8()
+	f.PDU = d.Bytes(n - 1)
+}
@@ -0,0 +1,58 @@
+package modbus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jon-ski/bitflux"
+)
+
+func TestTCPFrame(t *testing.T) {
+	adu := []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x11, 0x03, 0x00, 0x6B, 0x00, 0x03}
+	var buf bytes.Buffer
+	enc := bitflux.NewEncBE(&buf)
+	f := TCPFrame{Transaction: 1, Unit: 0x11, PDU: adu[MBAPHeaderSize:]}
+	f.EncodeBE(enc)
+	f.EncodeBE(enc)
+	if enc.Err != nil || !bytes.Equal(buf.Bytes(), append(append([]byte(nil), adu...), adu...)) {
+		t.Fatalf("got=% x err=%v", buf.Bytes(), enc.Err)
+	}
+
+	// Frames are read back to back from a stream.
+	dec := bitflux.NewDecBE(&buf)
+	for i := 0; i < 2; i++ {
+		var got TCPFrame
+		got.DecodeBE(dec)
+		if dec.Err != nil || got.Transaction != 1 || got.Unit != 0x11 || !bytes.Equal(got.PDU, f.PDU) {
+			t.Fatalf("frame %d: got=%+v err=%v", i, got, dec.Err)
+		}
+		if req, err := ParseRequest(got.PDU); err != nil || req.(*ReadRequest).Quantity != 3 {
+			t.Fatalf("ParseRequest: got=%+v err=%v", req, err)
+		}
+	}
+}
+
+func TestTCPFrameErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		adu  []byte
+		err  error
+	}{
+		{"Protocol", []byte{0x00, 0x01, 0x00, 0x01, 0x00, 0x02, 0x11, 0x07}, ErrProtocol},
+		{"ShortLength", []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x11}, ErrLength},
+		{"LongLength", []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0xFF, 0x11}, ErrLength},
+	} {
+		var f TCPFrame
+		dec := bitflux.NewDecBE(bytes.NewReader(tt.adu))
+		if f.DecodeBE(dec); !errors.Is(dec.Err, tt.err) {
+			t.Errorf("%s: got err=%v, want %v", tt.name, dec.Err, tt.err)
+		}
+	}
+
+	enc := bitflux.NewEncBE(&bytes.Buffer{})
+	(&TCPFrame{PDU: make([]byte, MaxPDU+1)}).EncodeBE(enc)
+	if !errors.Is(enc.Err, ErrLength) {
+		t.Errorf("oversized PDU: got err=%v, want ErrLength", enc.Err)
+	}
+}
@@ -0,0 +1,201 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// Packet is an MQTT control packet.
+type Packet interface {
+	// Type returns the packet type written to the fixed header.
+	Type() PacketType
+
+	// encode writes the variable header and payload to e and returns the fixed header flags.
+	encode(e *bitflux.EncBE, version byte) byte
+	// decode reads the variable header and payload described by h from d.
+	decode(d *bitflux.DecBE, h header)
+}
+
+// header is the part of the fixed header needed to decode a packet.
+type header struct {
+	flags   byte  // Low nibble of the first byte
+	size    int64 // Remaining length
+	version byte  // Protocol version of the connection
+}
+
+// fixedFlags returns the required fixed header flags of packets of type t, which is not PUBLISH.
+func fixedFlags(t PacketType) byte {
+	switch t {
+	case TypePubrel, TypeSubscribe, TypeUnsubscribe:
+		return 0x2
+	}
+	return 0
+}
+
+// newPacket returns an empty packet of type t, or nil if t is reserved.
+func newPacket(t PacketType) Packet {
+	switch t {
+	case TypeConnect:
+		return new(Connect)
+	case TypeConnack:
+		return new(Connack)
+	case TypePublish:
+		return new(Publish)
+	case TypePuback, TypePubrec, TypePubrel, TypePubcomp:
+		return &Ack{PacketType: t}
+	case TypeSubscribe:
+		return new(Subscribe)
+	case TypeSuback:
+		return new(Suback)
+	case TypeUnsubscribe:
+		return new(Unsubscribe)
+	case TypeUnsuback:
+		return new(Unsuback)
+	case TypePingreq:
+		return new(Pingreq)
+	case TypePingresp:
+		return new(Pingresp)
+	case TypeDisconnect:
+		return new(Disconnect)
+	case TypeAuth:
+		return new(Auth)
+	}
+	return nil
+}
+
+// DefaultMaxPacket is the packet size limit of Read when Codec.MaxPacket is 0.
+const DefaultMaxPacket = 1 << 20
+
+// readChunk is the largest part of a packet body read at once, so the body buffer grows
+// with the data that actually arrives rather than with the announced remaining length.
+const readChunk = 64 * 1024
+
+// Codec reads and writes the control packets of one connection.
+//
+// CONNECT packets carry their own protocol version and are read and written
+// regardless of Version; servers set Version from the Connect they read. The
+// other packets require Version to be Version311 or Version5.
+type Codec struct {
+	Version   byte // Protocol version: Version311 or Version5
+	MaxPacket int  // Largest packet Read accepts, fixed header included; 0 means DefaultMaxPacket, negative means no limit besides MaxVarint
+
+	body bytes.Reader
+	dec  bitflux.DecBE
+}
+
+// maxPacket returns the packet size limit of Read, or a negative value for none.
+func (c *Codec) maxPacket() int {
+	if c.MaxPacket == 0 {
+		return DefaultMaxPacket
+	}
+	return c.MaxPacket
+}
+
+// readBody reads a packet body of n bytes from d in chunks of at most readChunk bytes.
+func readBody(d *bitflux.DecBE, n int) []byte {
+	if n <= readChunk {
+		return d.Bytes(n)
+	}
+	var body []byte
+	for len(body) < n && d.Err == nil {
+		body = append(body, d.Bytes(min(n-len(body), readChunk))...)
+	}
+	return body
+}
+
+// checkVersion returns an error wrapping ErrVersion if packets of type t cannot be used with the codec's version.
+func (c *Codec) checkVersion(t PacketType) error {
+	switch {
+	case t == TypeConnect:
+	case c.Version != Version311 && c.Version != Version5:
+		return fmt.Errorf("%w: %d", ErrVersion, c.Version)
+	case t == TypeAuth && c.Version != Version5:
+		return fmt.Errorf("%w: AUTH packet in MQTT 3.1.1", ErrMalformed)
+	}
+	return nil
+}
+
+// Read reads one packet from d. It returns nil and sets d.Err on failure, to io.EOF
+// if d ends before the packet and io.ErrUnexpectedEOF if it ends within the packet.
+// Packets are read whole, so payloads do not alias d's buffers.
+func (c *Codec) Read(d *bitflux.DecBE) Packet {
+	b := d.U8()
+	if d.Err != nil {
+		return nil
+	}
+	defer func() {
+		if d.Err == io.EOF {
+			d.Err = io.ErrUnexpectedEOF
+		}
+	}()
+	n := DecodeVarint(d)
+	if d.Err != nil {
+		return nil
+	}
+	t := PacketType(b >> 4)
+	p := newPacket(t)
+	switch {
+	case p == nil:
+		d.Err = fmt.Errorf("%w: reserved packet type 0", ErrMalformed)
+	case c.maxPacket() > 0 && 1+varintLen(n)+int(n) > c.maxPacket():
+		d.Err = fmt.Errorf("%w: %v packet of %d bytes", ErrPacketTooLarge, t, 1+varintLen(n)+int(n))
+	case t != TypePublish && b&0xF != fixedFlags(t):
+		d.Err = fmt.Errorf("%w: %v flags %#x", ErrMalformed, t, b&0xF)
+	default:
+		d.Err = c.checkVersion(t)
+	}
+	if d.Err != nil {
+		return nil
+	}
+	body := readBody(d, int(n))
+	if d.Err != nil {
+		return nil
+	}
+
+	c.body.Reset(body)
+	c.dec.Reset(&c.body)
+	p.decode(&c.dec, header{flags: b & 0xF, size: int64(n), version: c.Version})
+	switch err := c.dec.Err; {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		d.Err = fmt.Errorf("%w: %v fields overrun the remaining length", ErrMalformed, t)
+	case err != nil:
+		d.Err = err
+	case c.dec.N != int64(n):
+		d.Err = fmt.Errorf("%w: %d trailing bytes in %v", ErrMalformed, int64(n)-c.dec.N, t)
+	}
+	if d.Err != nil {
+		return nil
+	}
+	return p
+}
+
+// Write writes p to e with its fixed header. Packets that break the specification
+// set e.Err and are not written.
+func (c *Codec) Write(e *bitflux.EncBE, p Packet) {
+	if e.Err != nil {
+		return
+	}
+	t := p.Type()
+	if e.Err = c.checkVersion(t); e.Err != nil {
+		return
+	}
+	body := bitflux.GetEncBE()
+	defer body.Release()
+	flags := p.encode(&body.EncBE, c.Version)
+	if body.Err != nil {
+		e.Err = body.Err
+		return
+	}
+	n := len(body.Bytes())
+	if n > MaxVarint {
+		e.Err = fmt.Errorf("%w: %v remaining length %d", ErrPacketTooLarge, t, n)
+		return
+	}
+	e.U8(byte(t)<<4 | flags)
+	EncodeVarint(e, uint32(n))
+	e.Write(body.Bytes())
+}
@@ -0,0 +1,303 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/jon-ski/bitflux"
+)
+
+func write(c *Codec, p Packet) ([]byte, error) {
+	var buf bytes.Buffer
+	e := bitflux.NewEncBE(&buf)
+	c.Write(e, p)
+	return buf.Bytes(), e.Err
+}
+
+func read(c *Codec, wire []byte) (Packet, error) {
+	d := bitflux.NewDecBE(bytes.NewReader(wire))
+	p := c.Read(d)
+	return p, d.Err
+}
+
+func TestPacketWire(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		version byte
+		packet  Packet
+		wire    []byte
+	}{
+		{"Connect311", Version311,
+			&Connect{Version: Version311, CleanStart: true, KeepAlive: 60, ClientID: "abc"},
+			[]byte{0x10, 0x0F, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x3C, 0x00, 0x03, 'a', 'b', 'c'}},
+		{"Connack5", Version5,
+			&Connack{Properties: Properties{{ID: ReceiveMaximum, Int: 10}}},
+			[]byte{0x20, 0x06, 0x00, 0x00, 0x03, 0x21, 0x00, 0x0A}},
+		{"Publish311", Version311,
+			&Publish{QoS: 1, Topic: "a/b", PacketID: 10, Payload: []byte("hi")},
+			[]byte{0x32, 0x09, 0x00, 0x03, 'a', '/', 'b', 0x00, 0x0A, 'h', 'i'}},
+		{"Publish5", Version5,
+			&Publish{Retain: true, Topic: "t", Payload: []byte{0xFF}},
+			[]byte{0x31, 0x05, 0x00, 0x01, 't', 0x00, 0xFF}},
+		{"PubackShort", Version5,
+			&Ack{PacketType: TypePuback, PacketID: 10},
+			[]byte{0x40, 0x02, 0x00, 0x0A}},
+		{"PubackReason", Version5,
+			&Ack{PacketType: TypePuback, PacketID: 10, ReasonCode: NoMatchingSubscribers},
+			[]byte{0x40, 0x03, 0x00, 0x0A, 0x10}},
+		{"Pubrel", Version311,
+			&Ack{PacketType: TypePubrel, PacketID: 1},
+			[]byte{0x62, 0x02, 0x00, 0x01}},
+		{"Subscribe311", Version311,
+			&Subscribe{PacketID: 1, Subscriptions: []Subscription{{Filter: "a/#", QoS: 1}}},
+			[]byte{0x82, 0x08, 0x00, 0x01, 0x00, 0x03, 'a', '/', '#', 0x01}},
+		{"Subscribe5", Version5,
+			&Subscribe{PacketID: 1, Subscriptions: []Subscription{{Filter: "a", QoS: 2, NoLocal: true, RetainHandling: 2}}},
+			[]byte{0x82, 0x07, 0x00, 0x01, 0x00, 0x00, 0x01, 'a', 0x26}},
+		{"Suback", Version311,
+			&Suback{PacketID: 1, ReasonCodes: []ReasonCode{GrantedQoS1, UnspecifiedError}},
+			[]byte{0x90, 0x04, 0x00, 0x01, 0x01, 0x80}},
+		{"Unsubscribe", Version311,
+			&Unsubscribe{PacketID: 2, Filters: []string{"a", "b/+"}},
+			[]byte{0xA2, 0x0A, 0x00, 0x02, 0x00, 0x01, 'a', 0x00, 0x03, 'b', '/', '+'}},
+		{"Unsuback311", Version311, &Unsuback{PacketID: 2}, []byte{0xB0, 0x02, 0x00, 0x02}},
+		{"Pingreq", Version311, &Pingreq{}, []byte{0xC0, 0x00}},
+		{"Pingresp", Version5, &Pingresp{}, []byte{0xD0, 0x00}},
+		{"Disconnect311", Version311, &Disconnect{}, []byte{0xE0, 0x00}},
+		{"DisconnectWill", Version5, &Disconnect{ReasonCode: DisconnectWithWillMessage}, []byte{0xE0, 0x01, 0x04}},
+		{"Auth", Version5,
+			&Auth{ReasonCode: ContinueAuthentication, Properties: Properties{{ID: AuthenticationMethod, Str: "x"}}},
+			[]byte{0xF0, 0x06, 0x18, 0x04, 0x15, 0x00, 0x01, 'x'}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Codec{Version: tt.version}
+			wire, err := write(c, tt.packet)
+			if err != nil || !bytes.Equal(wire, tt.wire) {
+				t.Fatalf("Write: got=% x err=%v, want=% x", wire, err, tt.wire)
+			}
+			p, err := read(c, tt.wire)
+			if err != nil || !reflect.DeepEqual(p, tt.packet) {
+				t.Fatalf("Read: got=%+v err=%v, want=%+v", p, err, tt.packet)
+			}
+		})
+	}
+}
+
+func TestPacketRoundTrip5(t *testing.T) {
+	c := &Codec{Version: Version5}
+	for _, p := range []Packet{
+		&Connect{
+			Version: Version5, KeepAlive: 30, ClientID: "client-1",
+			Properties: Properties{
+				{ID: SessionExpiryInterval, Int: 3600},
+				{ID: ReceiveMaximum, Int: 20},
+				{ID: UserProperty, Str: "region", Value: "eu"},
+			},
+			Will: &Will{
+				QoS: 1, Retain: true, Topic: "clients/1/status", Payload: []byte("offline"),
+				Properties: Properties{{ID: WillDelayInterval, Int: 5}, {ID: ContentType, Str: "text/plain"}},
+			},
+			HasUsername: true, Username: "user",
+			HasPassword: true, Password: []byte{0x01, 0x02},
+		},
+		&Connack{SessionPresent: true, Properties: Properties{
+			{ID: AssignedClientIdentifier, Str: "auto-7"},
+			{ID: MaximumQoS, Int: 1},
+			{ID: MaximumPacketSize, Int: 1 << 20},
+		}},
+		&Publish{Dup: true, QoS: 2, Topic: "a/b", PacketID: 0xFFFF, Payload: []byte("payload"), Properties: Properties{
+			{ID: MessageExpiryInterval, Int: 60},
+			{ID: CorrelationData, Data: []byte{0xCA, 0xFE}},
+			{ID: SubscriptionIdentifier, Int: 1},
+			{ID: SubscriptionIdentifier, Int: 268435455},
+		}},
+		&Publish{Topic: "", Properties: Properties{{ID: TopicAlias, Int: 3}}},
+		&Ack{PacketType: TypePubrec, PacketID: 9, ReasonCode: QuotaExceeded, Properties: Properties{{ID: ReasonString, Str: "full"}}},
+		&Subscribe{PacketID: 3, Properties: Properties{{ID: SubscriptionIdentifier, Int: 300}}, Subscriptions: []Subscription{
+			{Filter: "a/+", QoS: 1, RetainAsPublished: true},
+			{Filter: "#"},
+		}},
+		&Suback{PacketID: 3, ReasonCodes: []ReasonCode{GrantedQoS1, NotAuthorized}},
+		&Unsubscribe{PacketID: 4, Filters: []string{"a/+"}, Properties: Properties{{ID: UserProperty, Str: "k", Value: ""}}},
+		&Unsuback{PacketID: 4, ReasonCodes: []ReasonCode{NoSubscriptionExisted}},
+		&Disconnect{ReasonCode: ServerMoved, Properties: Properties{{ID: ServerReference, Str: "other:1883"}}},
+	} {
+		wire, err := write(c, p)
+		if err != nil {
+			t.Fatalf("Write(%v): %v", p.Type(), err)
+		}
+		got, err := read(c, wire)
+		if err != nil || !reflect.DeepEqual(got, p) {
+			t.Fatalf("Read(%v): got=%+v err=%v, want=%+v", p.Type(), got, err, p)
+		}
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	c := &Codec{Version: Version311}
+	var buf bytes.Buffer
+	e := bitflux.NewEncBE(&buf)
+	c.Write(e, &Pingreq{})
+	c.Write(e, &Publish{Topic: "x", Payload: make([]byte, 200)})
+	c.Write(e, &Disconnect{})
+	if e.Err != nil {
+		t.Fatal(e.Err)
+	}
+
+	d := bitflux.NewDecBE(&buf)
+	for _, want := range []PacketType{TypePingreq, TypePublish, TypeDisconnect} {
+		if p := c.Read(d); d.Err != nil || p.Type() != want {
+			t.Fatalf("Read: got=%+v err=%v, want %v", p, d.Err, want)
+		}
+	}
+	if c.Read(d); d.Err != io.EOF {
+		t.Fatalf("end of stream: got err=%v, want io.EOF", d.Err)
+	}
+
+	for _, wire := range [][]byte{{0x30}, {0x30, 0x80}, {0x30, 0x05, 0x00}} {
+		if _, err := read(c, wire); err != io.ErrUnexpectedEOF {
+			t.Errorf("truncated % x: got err=%v, want io.ErrUnexpectedEOF", wire, err)
+		}
+	}
+}
+
+func TestReadHugeLength(t *testing.T) {
+	// A header announcing the largest remaining length is rejected before the body is read.
+	wire := []byte{0x30, 0xFF, 0xFF, 0xFF, 0x7F, 0x00, 0x01, 'a'}
+	if _, err := read(&Codec{Version: Version311}, wire); !errors.Is(err, ErrPacketTooLarge) {
+		t.Fatalf("default limit: got err=%v, want ErrPacketTooLarge", err)
+	}
+
+	// Without a limit the body buffer only grows with the data that arrives.
+	c := &Codec{Version: Version311, MaxPacket: -1}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	p, err := read(c, wire)
+	runtime.ReadMemStats(&after)
+	if err != io.ErrUnexpectedEOF || p != nil {
+		t.Fatalf("no limit: got=%v err=%v, want io.ErrUnexpectedEOF", p, err)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("no limit: allocated %d bytes for a 3-byte body", n)
+	}
+
+	// Bodies larger than one chunk are read whole.
+	big := &Publish{Topic: "t", Payload: bytes.Repeat([]byte{0xAB}, 3*readChunk+5)}
+	wire, err = write(c, big)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err = read(c, wire); err != nil || !reflect.DeepEqual(p, big) {
+		t.Fatalf("large packet: err=%v", err)
+	}
+	if _, err = read(&Codec{Version: Version311}, wire); err != nil {
+		t.Fatalf("large packet within DefaultMaxPacket: %v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		codec Codec
+		wire  []byte
+		err   error
+	}{
+		{"ReservedType", Codec{Version: Version5}, []byte{0x00, 0x00}, ErrMalformed},
+		{"SubscribeFlags", Codec{Version: Version311}, []byte{0x80, 0x08, 0x00, 0x01, 0x00, 0x03, 'a', '/', '#', 0x01}, ErrMalformed},
+		{"PingFlags", Codec{Version: Version311}, []byte{0xC1, 0x00}, ErrMalformed},
+		{"QoS3", Codec{Version: Version311}, []byte{0x36, 0x05, 0x00, 0x01, 'a', 0x00, 0x01}, ErrMalformed},
+		{"DupQoS0", Codec{Version: Version311}, []byte{0x38, 0x03, 0x00, 0x01, 'a'}, ErrProtocol},
+		{"TopicWildcard", Codec{Version: Version311}, []byte{0x30, 0x03, 0x00, 0x01, '#'}, ErrProtocol},
+		{"EmptyTopic", Codec{Version: Version5}, []byte{0x30, 0x03, 0x00, 0x00, 0x00}, ErrProtocol},
+		{"TopicUTF8", Codec{Version: Version311}, []byte{0x30, 0x04, 0x00, 0x02, 0xC3, 0x28}, ErrMalformed},
+		{"PacketID0", Codec{Version: Version311}, []byte{0x40, 0x02, 0x00, 0x00}, ErrProtocol},
+		{"Trailing", Codec{Version: Version311}, []byte{0xC0, 0x01, 0x00}, ErrMalformed},
+		{"Overrun", Codec{Version: Version311}, []byte{0x40, 0x01, 0x00}, ErrMalformed},
+		{"PubackReason311", Codec{Version: Version311}, []byte{0x40, 0x03, 0x00, 0x0A, 0x10}, ErrMalformed},
+		{"Auth311", Codec{Version: Version311}, []byte{0xF0, 0x00}, ErrMalformed},
+		{"NoVersion", Codec{}, []byte{0xC0, 0x00}, ErrVersion},
+		{"TooLarge", Codec{Version: Version311, MaxPacket: 4}, []byte{0x30, 0x03, 0x00, 0x01, 'a'}, ErrPacketTooLarge},
+		{"ProtocolName", Codec{}, []byte{0x10, 0x07, 0x00, 0x04, 'M', 'Q', 'T', 'X', 0x04}, ErrVersion},
+		{"ProtocolLevel", Codec{}, []byte{0x10, 0x07, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x03}, ErrVersion},
+		{"ConnectReserved", Codec{}, []byte{0x10, 0x0C, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x01, 0x00, 0x00, 0x00, 0x00}, ErrMalformed},
+		{"WillQoSWithoutWill", Codec{}, []byte{0x10, 0x0C, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x08, 0x00, 0x00, 0x00, 0x00}, ErrMalformed},
+		{"PasswordWithoutUser", Codec{}, []byte{0x10, 0x0E, 0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, ErrProtocol},
+		{"ConnackFlags", Codec{Version: Version311}, []byte{0x20, 0x02, 0x02, 0x00}, ErrMalformed},
+		{"SessionPresentRefused", Codec{Version: Version311}, []byte{0x20, 0x02, 0x01, 0x05}, ErrProtocol},
+		{"SubscribeEmpty", Codec{Version: Version311}, []byte{0x82, 0x02, 0x00, 0x01}, ErrProtocol},
+		{"SubscribeOptions311", Codec{Version: Version311}, []byte{0x82, 0x06, 0x00, 0x01, 0x00, 0x01, 'a', 0x04}, ErrMalformed},
+		{"RetainHandling3", Codec{Version: Version5}, []byte{0x82, 0x07, 0x00, 0x01, 0x00, 0x00, 0x01, 'a', 0x30}, ErrProtocol},
+		{"SubackCode311", Codec{Version: Version311}, []byte{0x90, 0x03, 0x00, 0x01, 0x87}, ErrProtocol},
+		{"PropertyNotAllowed", Codec{Version: Version5}, []byte{0x40, 0x07, 0x00, 0x0A, 0x00, 0x03, 0x23, 0x00, 0x01}, ErrProtocol},
+		{"UnknownProperty", Codec{Version: Version5}, []byte{0x40, 0x05, 0x00, 0x0A, 0x00, 0x01, 0x7F}, ErrMalformed},
+		{"DuplicateProperty", Codec{Version: Version5}, []byte{0xE0, 0x0C, 0x00, 0x0A, 0x11, 0x00, 0x00, 0x00, 0x01, 0x11, 0x00, 0x00, 0x00, 0x02}, ErrProtocol},
+		{"PropertyOverrun", Codec{Version: Version5}, []byte{0xE0, 0x05, 0x00, 0x02, 0x11, 0x00, 0x00}, ErrMalformed},
+	} {
+		if p, err := read(&tt.codec, tt.wire); !errors.Is(err, tt.err) || p != nil {
+			t.Errorf("%s: got=%+v err=%v, want %v", tt.name, p, err, tt.err)
+		}
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		version byte
+		packet  Packet
+		err     error
+	}{
+		{"QoS3", Version5, &Publish{Topic: "a", QoS: 3, PacketID: 1}, ErrMalformed},
+		{"DupQoS0", Version5, &Publish{Topic: "a", Dup: true}, ErrProtocol},
+		{"PacketID0", Version5, &Publish{Topic: "a", QoS: 1}, ErrProtocol},
+		{"EmptyTopic", Version5, &Publish{}, ErrProtocol},
+		{"EmptyTopic311", Version311, &Publish{Properties: nil}, ErrProtocol},
+		{"Properties311", Version311, &Publish{Topic: "a", Properties: Properties{{ID: TopicAlias, Int: 1}}}, ErrProtocol},
+		{"PropertyNotAllowed", Version5, &Publish{Topic: "a", Properties: Properties{{ID: ServerKeepAlive, Int: 1}}}, ErrProtocol},
+		{"PropertyValue", Version5, &Publish{Topic: "a", Properties: Properties{{ID: TopicAlias, Int: 0x10000}}}, ErrProtocol},
+		{"UnknownProperty", Version5, &Disconnect{Properties: Properties{{ID: 0x7F}}}, ErrMalformed},
+		{"SubscribeEmpty", Version311, &Subscribe{PacketID: 1}, ErrProtocol},
+		{"SubscribeFilter", Version311, &Subscribe{PacketID: 1, Subscriptions: []Subscription{{Filter: "a/#/b"}}}, ErrProtocol},
+		{"SubscribeOptions311", Version311, &Subscribe{PacketID: 1, Subscriptions: []Subscription{{Filter: "a", NoLocal: true}}}, ErrProtocol},
+		{"UnsubscribeEmpty", Version5, &Unsubscribe{PacketID: 1}, ErrProtocol},
+		{"NotAnAck", Version5, &Ack{PacketType: TypeConnack, PacketID: 1}, ErrProtocol},
+		{"AckReason311", Version311, &Ack{PacketType: TypePuback, PacketID: 1, ReasonCode: NotAuthorized}, ErrProtocol},
+		{"Auth311", Version311, &Auth{}, ErrMalformed},
+		{"ConnectVersion", Version5, &Connect{Version: 3}, ErrVersion},
+		{"WillQoS", Version5, &Connect{Version: Version5, Will: &Will{Topic: "a", QoS: 3}}, ErrMalformed},
+		{"WillTopic", Version5, &Connect{Version: Version5, Will: &Will{Topic: "a/+"}}, ErrProtocol},
+		{"PasswordWithoutUser", Version5, &Connect{Version: Version311, HasPassword: true}, ErrProtocol},
+		{"ClientID", Version5, &Connect{Version: Version5, ClientID: "a\x00"}, ErrMalformed},
+		{"ConnackCode311", Version311, &Connack{ReasonCode: NotAuthorized}, ErrProtocol},
+		{"NoVersion", 0, &Pingreq{}, ErrVersion},
+	} {
+		wire, err := write(&Codec{Version: tt.version}, tt.packet)
+		if !errors.Is(err, tt.err) || len(wire) != 0 {
+			t.Errorf("%s: got=% x err=%v, want %v", tt.name, wire, err, tt.err)
+		}
+	}
+}
+
+func TestConnectVersion(t *testing.T) {
+	// A server reads CONNECT before it knows the client's version.
+	client := &Codec{Version: Version5}
+	wire, err := write(client, &Connect{Version: Version5, ClientID: "c", Properties: Properties{{ID: SessionExpiryInterval, Int: 10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var server Codec
+	p, err := read(&server, wire)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Version = p.(*Connect).Version
+	if server.Version != Version5 {
+		t.Fatalf("got version %d", server.Version)
+	}
+	if v, ok := p.(*Connect).Properties.Get(SessionExpiryInterval); !ok || v.Int != 10 {
+		t.Fatalf("Get: got=%+v, %v", v, ok)
+	}
+}
@@ -0,0 +1,136 @@
+package mqtt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// DecodeVarint reads a variable byte integer of one to four bytes from d.
+// Longer or non-minimal encodings set d.Err to an error wrapping ErrMalformed.
+func DecodeVarint(d *bitflux.DecBE) uint32 {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		b := d.U8()
+		if d.Err != nil {
+			return 0
+		}
+		if i > 0 && b == 0 {
+			d.Err = fmt.Errorf("%w: non-minimal variable byte integer", ErrMalformed)
+			return 0
+		}
+		v |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	d.Err = fmt.Errorf("%w: variable byte integer longer than 4 bytes", ErrMalformed)
+	return 0
+}
+
+// EncodeVarint writes v as a variable byte integer to e.
+// Values above MaxVarint set e.Err to an error wrapping ErrMalformed.
+func EncodeVarint(e *bitflux.EncBE, v uint32) {
+	if e.Err != nil {
+		return
+	}
+	if v > MaxVarint {
+		e.Err = fmt.Errorf("%w: variable byte integer %d", ErrMalformed, v)
+		return
+	}
+	for {
+		b := byte(v & 0x7F)
+		v >>= 7
+		if v == 0 {
+			e.U8(b)
+			return
+		}
+		e.U8(b | 0x80)
+	}
+}
+
+// varintLen returns the encoded size of a variable byte integer.
+func varintLen(v uint32) int {
+	n := 1
+	for ; v > 0x7F; v >>= 7 {
+		n++
+	}
+	return n
+}
+
+// validString reports whether b is well-formed UTF-8 without U+0000, as required of UTF-8 encoded strings.
+func validString(b []byte) bool { return utf8.Valid(b) && bytes.IndexByte(b, 0) < 0 }
+
+// DecodeString reads a UTF-8 encoded string, a two-byte length followed by its bytes, from d.
+// Ill-formed UTF-8 and U+0000 set d.Err to an error wrapping ErrMalformed.
+func DecodeString(d *bitflux.DecBE) string {
+	b := d.Bytes(int(d.U16()))
+	if d.Err != nil {
+		return ""
+	}
+	if !validString(b) {
+		d.Err = fmt.Errorf("%w: invalid UTF-8 string %q", ErrMalformed, b)
+		return ""
+	}
+	return string(b)
+}
+
+// EncodeString writes s as a UTF-8 encoded string to e. Strings longer than 65535
+// bytes, ill-formed UTF-8 and U+0000 set e.Err to an error wrapping ErrMalformed.
+func EncodeString(e *bitflux.EncBE, s string) {
+	if e.Err != nil {
+		return
+	}
+	if len(s) > 0xFFFF || !validString([]byte(s)) {
+		e.Err = fmt.Errorf("%w: invalid UTF-8 string %q", ErrMalformed, s)
+		return
+	}
+	e.U16(uint16(len(s)))
+	e.Write([]byte(s))
+}
+
+// DecodeBinary reads binary data, a two-byte length followed by the data, from d.
+func DecodeBinary(d *bitflux.DecBE) []byte {
+	return d.Bytes(int(d.U16()))
+}
+
+// EncodeBinary writes b as binary data to e.
+// Data longer than 65535 bytes sets e.Err to an error wrapping ErrMalformed.
+func EncodeBinary(e *bitflux.EncBE, b []byte) {
+	if e.Err != nil {
+		return
+	}
+	if len(b) > 0xFFFF {
+		e.Err = fmt.Errorf("%w: binary data of %d bytes", ErrMalformed, len(b))
+		return
+	}
+	e.U16(uint16(len(b)))
+	e.Write(b)
+}
+
+// checkTopic returns an error wrapping ErrProtocol if name contains wildcards.
+// Empty names are checked by the caller, as MQTT 5.0 allows them with a topic alias.
+func checkTopic(name string) error {
+	if strings.ContainsAny(name, "+#") {
+		return fmt.Errorf("%w: wildcard in topic name %q", ErrProtocol, name)
+	}
+	return nil
+}
+
+// checkFilter returns an error wrapping ErrProtocol if filter is empty or uses wildcards
+// other than as a whole level, with # only as the last level.
+func checkFilter(filter string) error {
+	if filter == "" {
+		return fmt.Errorf("%w: empty topic filter", ErrProtocol)
+	}
+	levels := strings.Split(filter, "/")
+	for i, l := range levels {
+		if l != "+" && l != "#" && strings.ContainsAny(l, "+#") || l == "#" && i != len(levels)-1 {
+			return fmt.Errorf("%w: invalid topic filter %q", ErrProtocol, filter)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package mqtt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/jon-ski/bitflux"
+)
+
+func TestVarint(t *testing.T) {
+	for _, tt := range []struct {
+		v    uint32
+		wire []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xFF, 0x7F}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xFF, 0xFF, 0x7F}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+		{MaxVarint, []byte{0xFF, 0xFF, 0xFF, 0x7F}},
+	} {
+		var buf bytes.Buffer
+		e := bitflux.NewEncBE(&buf)
+		if EncodeVarint(e, tt.v); e.Err != nil || !bytes.Equal(buf.Bytes(), tt.wire) {
+			t.Errorf("EncodeVarint(%d): got=% x err=%v, want=% x", tt.v, buf.Bytes(), e.Err, tt.wire)
+		}
+		if n := varintLen(tt.v); n != len(tt.wire) {
+			t.Errorf("varintLen(%d): got=%d, want=%d", tt.v, n, len(tt.wire))
+		}
+		d := bitflux.NewDecBE(bytes.NewReader(tt.wire))
+		if v := DecodeVarint(d); d.Err != nil || v != tt.v {
+			t.Errorf("DecodeVarint(% x): got=%d err=%v, want=%d", tt.wire, v, d.Err, tt.v)
+		}
+	}
+
+	for _, wire := range [][]byte{{0xFF, 0xFF, 0xFF, 0xFF, 0x01}, {0x80, 0x00}} {
+		d := bitflux.NewDecBE(bytes.NewReader(wire))
+		if DecodeVarint(d); !errors.Is(d.Err, ErrMalformed) {
+			t.Errorf("DecodeVarint(% x): got err=%v, want ErrMalformed", wire, d.Err)
+		}
+	}
+	e := bitflux.NewEncBE(&bytes.Buffer{})
+	if EncodeVarint(e, MaxVarint+1); !errors.Is(e.Err, ErrMalformed) {
+		t.Errorf("EncodeVarint(MaxVarint+1): got err=%v, want ErrMalformed", e.Err)
+	}
+}
+
+func TestString(t *testing.T) {
+	// Example from the specification: "A\U0002A6D4".
+	wire := []byte{0x00, 0x05, 0x41, 0xF0, 0xAA, 0x9B, 0x94}
+	var buf bytes.Buffer
+	e := bitflux.NewEncBE(&buf)
+	if EncodeString(e, "A\U0002A6D4"); e.Err != nil || !bytes.Equal(buf.Bytes(), wire) {
+		t.Fatalf("EncodeString: got=% x err=%v", buf.Bytes(), e.Err)
+	}
+	d := bitflux.NewDecBE(bytes.NewReader(wire))
+	if s := DecodeString(d); d.Err != nil || s != "A\U0002A6D4" {
+		t.Fatalf("DecodeString: got=%q err=%v", s, d.Err)
+	}
+
+	for _, wire := range [][]byte{{0x00, 0x01, 0x00}, {0x00, 0x02, 0xC3, 0x28}, {0x00, 0x03, 0xED, 0xA0, 0x80}} {
+		d := bitflux.NewDecBE(bytes.NewReader(wire))
+		if DecodeString(d); !errors.Is(d.Err, ErrMalformed) {
+			t.Errorf("DecodeString(% x): got err=%v, want ErrMalformed", wire, d.Err)
+		}
+	}
+	for _, s := range []string{"a\x00b", "\xC3\x28", string(make([]byte, 0x10000))} {
+		e := bitflux.NewEncBE(&bytes.Buffer{})
+		if EncodeString(e, s); !errors.Is(e.Err, ErrMalformed) {
+			t.Errorf("EncodeString(%.8q): got err=%v, want ErrMalformed", s, e.Err)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	var buf bytes.Buffer
+	e := bitflux.NewEncBE(&buf)
+	EncodeBinary(e, []byte{0x00, 0xFF})
+	EncodeBinary(e, nil)
+	if e.Err != nil || !bytes.Equal(buf.Bytes(), []byte{0x00, 0x02, 0x00, 0xFF, 0x00, 0x00}) {
+		t.Fatalf("EncodeBinary: got=% x err=%v", buf.Bytes(), e.Err)
+	}
+	d := bitflux.NewDecBE(&buf)
+	if b := DecodeBinary(d); d.Err != nil || !bytes.Equal(b, []byte{0x00, 0xFF}) {
+		t.Fatalf("DecodeBinary: got=% x err=%v", b, d.Err)
+	}
+	if b := DecodeBinary(d); d.Err != nil || len(b) != 0 {
+		t.Fatalf("DecodeBinary empty: got=% x err=%v", b, d.Err)
+	}
+}
+
+func TestFilters(t *testing.T) {
+	for _, f := range []string{"#", "+", "/", "a/+/b", "sport/tennis/#", "+/+", "$SYS/#"} {
+		if err := checkFilter(f); err != nil {
+			t.Errorf("checkFilter(%q): %v", f, err)
+		}
+	}
+	for _, f := range []string{"", "a#", "a/#/b", "a+", "#/a"} {
+		if err := checkFilter(f); !errors.Is(err, ErrProtocol) {
+			t.Errorf("checkFilter(%q): got err=%v, want ErrProtocol", f, err)
+		}
+	}
+}
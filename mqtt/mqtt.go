@@ -0,0 +1,186 @@
+// Package mqtt encodes and decodes MQTT 3.1.1 and 5.0 control packets with
+// bitflux big-endian encoders and decoders.
+//
+// A Codec reads and writes whole packets, fixed header included, for one
+// protocol version. Spec violations found while reading or writing are
+// reported through the decoder's or encoder's Err, wrapping ErrMalformed,
+// ErrProtocol, ErrVersion or ErrPacketTooLarge:
+//
+//	c := mqtt.Codec{Version: mqtt.Version5}
+//	c.Write(enc, &mqtt.Publish{Topic: "sensors/1", QoS: 1, PacketID: 7, Payload: data})
+//	switch p := c.Read(dec).(type) {
+//	case *mqtt.Ack:
+//		...
+//	}
+//	if dec.Err != nil { ... }
+package mqtt
+
+import (
+	"errors"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// Protocol versions, as carried in the protocol level of CONNECT packets.
+const (
+	Version311 byte = 4 // MQTT 3.1.1
+	Version5   byte = 5 // MQTT 5.0
+)
+
+// MaxVarint is the largest value of a variable byte integer, and so of a packet's remaining length.
+const MaxVarint = 268435455
+
+// PacketType is the type of a control packet, from the high nibble of its first byte.
+type PacketType uint8
+
+// Control packet types.
+const (
+	TypeConnect     PacketType = 1
+	TypeConnack     PacketType = 2
+	TypePublish     PacketType = 3
+	TypePuback      PacketType = 4
+	TypePubrec      PacketType = 5
+	TypePubrel      PacketType = 6
+	TypePubcomp     PacketType = 7
+	TypeSubscribe   PacketType = 8
+	TypeSuback      PacketType = 9
+	TypeUnsubscribe PacketType = 10
+	TypeUnsuback    PacketType = 11
+	TypePingreq     PacketType = 12
+	TypePingresp    PacketType = 13
+	TypeDisconnect  PacketType = 14
+	TypeAuth        PacketType = 15 // MQTT 5.0 only
+)
+
+var packetTypes = bitflux.NewEnum("PacketType", 1, map[PacketType]string{
+	TypeConnect:     "CONNECT",
+	TypeConnack:     "CONNACK",
+	TypePublish:     "PUBLISH",
+	TypePuback:      "PUBACK",
+	TypePubrec:      "PUBREC",
+	TypePubrel:      "PUBREL",
+	TypePubcomp:     "PUBCOMP",
+	TypeSubscribe:   "SUBSCRIBE",
+	TypeSuback:      "SUBACK",
+	TypeUnsubscribe: "UNSUBSCRIBE",
+	TypeUnsuback:    "UNSUBACK",
+	TypePingreq:     "PINGREQ",
+	TypePingresp:    "PINGRESP",
+	TypeDisconnect:  "DISCONNECT",
+	TypeAuth:        "AUTH",
+})
+
+func (t PacketType) String() string { return packetTypes.String(t) }
+
+// ReasonCode reports the result of an operation in MQTT 5.0 packets. In MQTT
+// 3.1.1 it holds the CONNACK return code or the SUBACK return codes, which
+// share the values 0x00 to 0x02 and 0x80 with MQTT 5.0.
+type ReasonCode uint8
+
+// MQTT 5.0 reason codes. Success is also Normal disconnection and Granted QoS 0.
+const (
+	Success                             ReasonCode = 0x00
+	GrantedQoS1                         ReasonCode = 0x01
+	GrantedQoS2                         ReasonCode = 0x02
+	DisconnectWithWillMessage           ReasonCode = 0x04
+	NoMatchingSubscribers               ReasonCode = 0x10
+	NoSubscriptionExisted               ReasonCode = 0x11
+	ContinueAuthentication              ReasonCode = 0x18
+	ReAuthenticate                      ReasonCode = 0x19
+	UnspecifiedError                    ReasonCode = 0x80
+	MalformedPacket                     ReasonCode = 0x81
+	ProtocolError                       ReasonCode = 0x82
+	ImplementationSpecificError         ReasonCode = 0x83
+	UnsupportedProtocolVersion          ReasonCode = 0x84
+	ClientIdentifierNotValid            ReasonCode = 0x85
+	BadUserNameOrPassword               ReasonCode = 0x86
+	NotAuthorized                       ReasonCode = 0x87
+	ServerUnavailable                   ReasonCode = 0x88
+	ServerBusy                          ReasonCode = 0x89
+	Banned                              ReasonCode = 0x8A
+	ServerShuttingDown                  ReasonCode = 0x8B
+	BadAuthenticationMethod             ReasonCode = 0x8C
+	KeepAliveTimeout                    ReasonCode = 0x8D
+	SessionTakenOver                    ReasonCode = 0x8E
+	TopicFilterInvalid                  ReasonCode = 0x8F
+	TopicNameInvalid                    ReasonCode = 0x90
+	PacketIdentifierInUse               ReasonCode = 0x91
+	PacketIdentifierNotFound            ReasonCode = 0x92
+	ReceiveMaximumExceeded              ReasonCode = 0x93
+	TopicAliasInvalid                   ReasonCode = 0x94
+	PacketTooLarge                      ReasonCode = 0x95
+	MessageRateTooHigh                  ReasonCode = 0x96
+	QuotaExceeded                       ReasonCode = 0x97
+	AdministrativeAction                ReasonCode = 0x98
+	PayloadFormatInvalid                ReasonCode = 0x99
+	RetainNotSupported                  ReasonCode = 0x9A
+	QoSNotSupported                     ReasonCode = 0x9B
+	UseAnotherServer                    ReasonCode = 0x9C
+	ServerMoved                         ReasonCode = 0x9D
+	SharedSubscriptionsNotSupported     ReasonCode = 0x9E
+	ConnectionRateExceeded              ReasonCode = 0x9F
+	MaximumConnectTime                  ReasonCode = 0xA0
+	SubscriptionIdentifiersNotSupported ReasonCode = 0xA1
+	WildcardSubscriptionsNotSupported   ReasonCode = 0xA2
+)
+
+var reasonCodes = bitflux.NewEnum("ReasonCode", 1, map[ReasonCode]string{
+	Success:                             "Success",
+	GrantedQoS1:                         "GrantedQoS1",
+	GrantedQoS2:                         "GrantedQoS2",
+	DisconnectWithWillMessage:           "DisconnectWithWillMessage",
+	NoMatchingSubscribers:               "NoMatchingSubscribers",
+	NoSubscriptionExisted:               "NoSubscriptionExisted",
+	ContinueAuthentication:              "ContinueAuthentication",
+	ReAuthenticate:                      "ReAuthenticate",
+	UnspecifiedError:                    "UnspecifiedError",
+	MalformedPacket:                     "MalformedPacket",
+	ProtocolError:                       "ProtocolError",
+	ImplementationSpecificError:         "ImplementationSpecificError",
+	UnsupportedProtocolVersion:          "UnsupportedProtocolVersion",
+	ClientIdentifierNotValid:            "ClientIdentifierNotValid",
+	BadUserNameOrPassword:               "BadUserNameOrPassword",
+	NotAuthorized:                       "NotAuthorized",
+	ServerUnavailable:                   "ServerUnavailable",
+	ServerBusy:                          "ServerBusy",
+	Banned:                              "Banned",
+	ServerShuttingDown:                  "ServerShuttingDown",
+	BadAuthenticationMethod:             "BadAuthenticationMethod",
+	KeepAliveTimeout:                    "KeepAliveTimeout",
+	SessionTakenOver:                    "SessionTakenOver",
+	TopicFilterInvalid:                  "TopicFilterInvalid",
+	TopicNameInvalid:                    "TopicNameInvalid",
+	PacketIdentifierInUse:               "PacketIdentifierInUse",
+	PacketIdentifierNotFound:            "PacketIdentifierNotFound",
+	ReceiveMaximumExceeded:              "ReceiveMaximumExceeded",
+	TopicAliasInvalid:                   "TopicAliasInvalid",
+	PacketTooLarge:                      "PacketTooLarge",
+	MessageRateTooHigh:                  "MessageRateTooHigh",
+	QuotaExceeded:                       "QuotaExceeded",
+	AdministrativeAction:                "AdministrativeAction",
+	PayloadFormatInvalid:                "PayloadFormatInvalid",
+	RetainNotSupported:                  "RetainNotSupported",
+	QoSNotSupported:                     "QoSNotSupported",
+	UseAnotherServer:                    "UseAnotherServer",
+	ServerMoved:                         "ServerMoved",
+	SharedSubscriptionsNotSupported:     "SharedSubscriptionsNotSupported",
+	ConnectionRateExceeded:              "ConnectionRateExceeded",
+	MaximumConnectTime:                  "MaximumConnectTime",
+	SubscriptionIdentifiersNotSupported: "SubscriptionIdentifiersNotSupported",
+	WildcardSubscriptionsNotSupported:   "WildcardSubscriptionsNotSupported",
+})
+
+func (c ReasonCode) String() string { return reasonCodes.String(c) }
+
+var (
+	// ErrMalformed is reported for packets that cannot be parsed according to the
+	// specification, such as invalid flags, truncated fields or invalid UTF-8 strings.
+	ErrMalformed = errors.New("mqtt: malformed packet")
+	// ErrProtocol is reported for well-formed packets that break a protocol rule, such
+	// as a zero packet identifier or properties in an MQTT 3.1.1 packet.
+	ErrProtocol = errors.New("mqtt: protocol error")
+	// ErrVersion is reported for unsupported protocol names and versions.
+	ErrVersion = errors.New("mqtt: unsupported protocol version")
+	// ErrPacketTooLarge is reported for packets larger than the codec's or the protocol's limit.
+	ErrPacketTooLarge = errors.New("mqtt: packet too large")
+)
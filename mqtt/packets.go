@@ -0,0 +1,631 @@
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// protocolName is the protocol name of CONNECT packets in MQTT 3.1.1 and 5.0.
+const protocolName = "MQTT"
+
+// Connect flags.
+const (
+	connectCleanStart = 0x02
+	connectWill       = 0x04
+	connectWillRetain = 0x20
+	connectPassword   = 0x40
+	connectUsername   = 0x80
+)
+
+// decodePacketID reads a packet identifier, which must not be zero.
+func decodePacketID(d *bitflux.DecBE) uint16 {
+	id := d.U16()
+	if d.Err == nil && id == 0 {
+		d.Err = fmt.Errorf("%w: packet identifier 0", ErrProtocol)
+	}
+	return id
+}
+
+// encodePacketID writes a packet identifier, which must not be zero.
+func encodePacketID(e *bitflux.EncBE, id uint16) {
+	if e.Err == nil && id == 0 {
+		e.Err = fmt.Errorf("%w: packet identifier 0", ErrProtocol)
+	}
+	e.U16(id)
+}
+
+// encodeReason writes the optional reason code and properties that end MQTT 5.0
+// acknowledgements, DISCONNECT and AUTH packets. Both are left out when the reason
+// code is Success and there are no properties. MQTT 3.1.1 packets have neither.
+func encodeReason(e *bitflux.EncBE, rc ReasonCode, ps Properties, t PacketType, version byte) {
+	if e.Err != nil {
+		return
+	}
+	if version != Version5 {
+		if rc != Success || len(ps) > 0 {
+			e.Err = fmt.Errorf("%w: reason code or properties in MQTT 3.1.1 %v", ErrProtocol, t)
+		}
+		return
+	}
+	if rc == Success && len(ps) == 0 {
+		return
+	}
+	e.U8(uint8(rc))
+	if len(ps) > 0 {
+		encodeProperties(e, ps, t, version)
+	}
+}
+
+// decodeReason reads the optional reason code and properties written by encodeReason.
+func decodeReason(d *bitflux.DecBE, h header, t PacketType) (ReasonCode, Properties) {
+	if h.version != Version5 {
+		return Success, nil
+	}
+	var rc ReasonCode
+	var ps Properties
+	if d.N < h.size {
+		rc = ReasonCode(d.U8())
+	}
+	if d.N < h.size {
+		ps = decodeProperties(d, t, h.version)
+	}
+	return rc, ps
+}
+
+// Connect is the first packet a client sends on a connection.
+type Connect struct {
+	Version     byte   // Protocol version: Version311 or Version5
+	CleanStart  bool   // Clean Session in MQTT 3.1.1
+	KeepAlive   uint16 // Keep alive interval in seconds
+	Properties  Properties
+	ClientID    string
+	Will        *Will // Will message, or nil
+	HasUsername bool
+	Username    string
+	HasPassword bool // MQTT 3.1.1 requires a user name with a password
+	Password    []byte
+}
+
+// Will is the message a server publishes when a client disconnects ungracefully.
+type Will struct {
+	QoS        byte
+	Retain     bool
+	Properties Properties // MQTT 5.0 will properties
+	Topic      string
+	Payload    []byte
+}
+
+func (p *Connect) Type() PacketType { return TypeConnect }
+
+func (p *Connect) encode(e *bitflux.EncBE, _ byte) byte {
+	if p.Version != Version311 && p.Version != Version5 {
+		e.Err = fmt.Errorf("%w: %d", ErrVersion, p.Version)
+		return 0
+	}
+	var flags byte
+	if p.CleanStart {
+		flags |= connectCleanStart
+	}
+	if w := p.Will; w != nil {
+		if w.QoS > 2 {
+			e.Err = fmt.Errorf("%w: will QoS %d", ErrMalformed, w.QoS)
+			return 0
+		}
+		flags |= connectWill | w.QoS<<3
+		if w.Retain {
+			flags |= connectWillRetain
+		}
+	}
+	if p.HasUsername {
+		flags |= connectUsername
+	}
+	if p.HasPassword {
+		if !p.HasUsername && p.Version == Version311 {
+			e.Err = fmt.Errorf("%w: password without user name", ErrProtocol)
+			return 0
+		}
+		flags |= connectPassword
+	}
+	EncodeString(e, protocolName)
+	e.U8(p.Version)
+	e.U8(flags)
+	e.U16(p.KeepAlive)
+	encodeProperties(e, p.Properties, TypeConnect, p.Version)
+	EncodeString(e, p.ClientID)
+	if w := p.Will; w != nil {
+		encodeProperties(e, w.Properties, willProperties, p.Version)
+		if e.Err == nil && w.Topic == "" {
+			e.Err = fmt.Errorf("%w: empty will topic", ErrProtocol)
+		}
+		if e.Err == nil {
+			e.Err = checkTopic(w.Topic)
+		}
+		EncodeString(e, w.Topic)
+		EncodeBinary(e, w.Payload)
+	}
+	if p.HasUsername {
+		EncodeString(e, p.Username)
+	}
+	if p.HasPassword {
+		EncodeBinary(e, p.Password)
+	}
+	return 0
+}
+
+func (p *Connect) decode(d *bitflux.DecBE, _ header) {
+	name := DecodeString(d)
+	p.Version = d.U8()
+	if d.Err != nil {
+		return
+	}
+	if name != protocolName || p.Version != Version311 && p.Version != Version5 {
+		d.Err = fmt.Errorf("%w: %q level %d", ErrVersion, name, p.Version)
+		return
+	}
+	flags := d.U8()
+	if d.Err != nil {
+		return
+	}
+	willQoS := flags >> 3 & 3
+	switch {
+	case flags&1 != 0:
+		d.Err = fmt.Errorf("%w: reserved CONNECT flag set", ErrMalformed)
+	case willQoS == 3:
+		d.Err = fmt.Errorf("%w: will QoS 3", ErrMalformed)
+	case flags&connectWill == 0 && flags&(3<<3|connectWillRetain) != 0:
+		d.Err = fmt.Errorf("%w: will QoS or retain without will", ErrMalformed)
+	case p.Version == Version311 && flags&connectPassword != 0 && flags&connectUsername == 0:
+		d.Err = fmt.Errorf("%w: password without user name", ErrProtocol)
+	}
+	if d.Err != nil {
+		return
+	}
+	p.CleanStart = flags&connectCleanStart != 0
+	p.KeepAlive = d.U16()
+	p.Properties = decodeProperties(d, TypeConnect, p.Version)
+	p.ClientID = DecodeString(d)
+	if flags&connectWill != 0 {
+		w := &Will{QoS: willQoS, Retain: flags&connectWillRetain != 0}
+		w.Properties = decodeProperties(d, willProperties, p.Version)
+		w.Topic = DecodeString(d)
+		w.Payload = DecodeBinary(d)
+		if d.Err == nil {
+			d.Err = checkTopic(w.Topic)
+		}
+		p.Will = w
+	}
+	if p.HasUsername = flags&connectUsername != 0; p.HasUsername {
+		p.Username = DecodeString(d)
+	}
+	if p.HasPassword = flags&connectPassword != 0; p.HasPassword {
+		p.Password = DecodeBinary(d)
+	}
+}
+
+// Connack is the server's response to a CONNECT packet.
+type Connack struct {
+	SessionPresent bool
+	ReasonCode     ReasonCode // Return code in MQTT 3.1.1, from 0 to 5
+	Properties     Properties
+}
+
+func (p *Connack) Type() PacketType { return TypeConnack }
+
+// check returns an error if the fields break the specification.
+func (p *Connack) check(version byte) error {
+	switch {
+	case version == Version311 && p.ReasonCode > 5:
+		return fmt.Errorf("%w: CONNACK return code %d", ErrProtocol, p.ReasonCode)
+	case p.SessionPresent && p.ReasonCode != Success:
+		return fmt.Errorf("%w: session present with reason code %v", ErrProtocol, p.ReasonCode)
+	}
+	return nil
+}
+
+func (p *Connack) encode(e *bitflux.EncBE, version byte) byte {
+	if e.Err = p.check(version); e.Err != nil {
+		return 0
+	}
+	var flags uint8
+	if p.SessionPresent {
+		flags = 1
+	}
+	e.U8(flags)
+	e.U8(uint8(p.ReasonCode))
+	encodeProperties(e, p.Properties, TypeConnack, version)
+	return 0
+}
+
+func (p *Connack) decode(d *bitflux.DecBE, h header) {
+	flags := d.U8()
+	p.ReasonCode = ReasonCode(d.U8())
+	if d.Err != nil {
+		return
+	}
+	if flags&^1 != 0 {
+		d.Err = fmt.Errorf("%w: reserved CONNACK flags %#x", ErrMalformed, flags)
+		return
+	}
+	p.SessionPresent = flags == 1
+	if d.Err = p.check(h.version); d.Err != nil {
+		return
+	}
+	p.Properties = decodeProperties(d, TypeConnack, h.version)
+}
+
+// Publish carries an application message.
+type Publish struct {
+	Dup        bool // Redelivery of a QoS 1 or 2 message
+	QoS        byte
+	Retain     bool
+	Topic      string // May be empty in MQTT 5.0 if Properties hold a TopicAlias
+	PacketID   uint16 // QoS 1 and 2 only
+	Properties Properties
+	Payload    []byte
+}
+
+func (p *Publish) Type() PacketType { return TypePublish }
+
+// check returns an error if the fields break the specification.
+func (p *Publish) check(version byte) error {
+	switch {
+	case p.QoS > 2:
+		return fmt.Errorf("%w: QoS %d", ErrMalformed, p.QoS)
+	case p.Dup && p.QoS == 0:
+		return fmt.Errorf("%w: DUP flag with QoS 0", ErrProtocol)
+	case p.Topic == "":
+		if _, ok := p.Properties.Get(TopicAlias); !ok || version != Version5 {
+			return fmt.Errorf("%w: empty topic name without topic alias", ErrProtocol)
+		}
+	}
+	return checkTopic(p.Topic)
+}
+
+func (p *Publish) encode(e *bitflux.EncBE, version byte) byte {
+	if e.Err = p.check(version); e.Err != nil {
+		return 0
+	}
+	flags := p.QoS << 1
+	if p.Dup {
+		flags |= 0x8
+	}
+	if p.Retain {
+		flags |= 0x1
+	}
+	EncodeString(e, p.Topic)
+	if p.QoS > 0 {
+		encodePacketID(e, p.PacketID)
+	}
+	encodeProperties(e, p.Properties, TypePublish, version)
+	e.Write(p.Payload)
+	return flags
+}
+
+func (p *Publish) decode(d *bitflux.DecBE, h header) {
+	p.Dup = h.flags&0x8 != 0
+	p.QoS = h.flags >> 1 & 3
+	p.Retain = h.flags&0x1 != 0
+	if p.QoS == 3 {
+		d.Err = fmt.Errorf("%w: QoS 3", ErrMalformed)
+		return
+	}
+	p.Topic = DecodeString(d)
+	if p.QoS > 0 {
+		p.PacketID = decodePacketID(d)
+	}
+	p.Properties = decodeProperties(d, TypePublish, h.version)
+	if d.Err == nil {
+		d.Err = p.check(h.version)
+	}
+	p.Payload = d.Bytes(int(h.size - d.N))
+}
+
+// Ack acknowledges a PUBLISH packet (PUBACK and PUBREC) or a PUBREL packet
+// (PUBCOMP), or releases a PUBLISH packet (PUBREL).
+type Ack struct {
+	PacketType PacketType // TypePuback, TypePubrec, TypePubrel or TypePubcomp
+	PacketID   uint16
+	ReasonCode ReasonCode // MQTT 5.0 only
+	Properties Properties // MQTT 5.0 only
+}
+
+func (p *Ack) Type() PacketType { return p.PacketType }
+
+func (p *Ack) encode(e *bitflux.EncBE, version byte) byte {
+	switch p.PacketType {
+	case TypePuback, TypePubrec, TypePubrel, TypePubcomp:
+	default:
+		e.Err = fmt.Errorf("%w: %v is not an acknowledgement", ErrProtocol, p.PacketType)
+		return 0
+	}
+	encodePacketID(e, p.PacketID)
+	encodeReason(e, p.ReasonCode, p.Properties, p.PacketType, version)
+	return fixedFlags(p.PacketType)
+}
+
+func (p *Ack) decode(d *bitflux.DecBE, h header) {
+	p.PacketID = decodePacketID(d)
+	p.ReasonCode, p.Properties = decodeReason(d, h, p.PacketType)
+}
+
+// Subscription is a topic filter and the options of a subscription to it.
+type Subscription struct {
+	Filter            string
+	QoS               byte // Maximum QoS
+	NoLocal           bool // MQTT 5.0 only
+	RetainAsPublished bool // MQTT 5.0 only
+	RetainHandling    byte // MQTT 5.0 only, 0 to 2
+}
+
+// options returns the subscription options byte.
+func (s *Subscription) options(version byte) (byte, error) {
+	if s.QoS > 2 {
+		return 0, fmt.Errorf("%w: QoS %d for %q", ErrMalformed, s.QoS, s.Filter)
+	}
+	o := s.QoS
+	if s.NoLocal {
+		o |= 0x04
+	}
+	if s.RetainAsPublished {
+		o |= 0x08
+	}
+	if s.RetainHandling > 2 {
+		return 0, fmt.Errorf("%w: retain handling %d for %q", ErrProtocol, s.RetainHandling, s.Filter)
+	}
+	o |= s.RetainHandling << 4
+	if version != Version5 && o > 2 {
+		return 0, fmt.Errorf("%w: MQTT 5.0 subscription options for %q", ErrProtocol, s.Filter)
+	}
+	return o, nil
+}
+
+// setOptions sets the options from the subscription options byte o.
+func (s *Subscription) setOptions(o byte, version byte) error {
+	reserved := byte(0xC0)
+	if version != Version5 {
+		reserved = 0xFC
+	}
+	if o&reserved != 0 || o&3 == 3 {
+		return fmt.Errorf("%w: subscription options %#x for %q", ErrMalformed, o, s.Filter)
+	}
+	s.QoS = o & 3
+	s.NoLocal = o&0x04 != 0
+	s.RetainAsPublished = o&0x08 != 0
+	s.RetainHandling = o >> 4 & 3
+	if s.RetainHandling == 3 {
+		return fmt.Errorf("%w: retain handling 3 for %q", ErrProtocol, s.Filter)
+	}
+	return nil
+}
+
+// Subscribe requests one or more subscriptions.
+type Subscribe struct {
+	PacketID      uint16
+	Properties    Properties
+	Subscriptions []Subscription // At least one
+}
+
+func (p *Subscribe) Type() PacketType { return TypeSubscribe }
+
+func (p *Subscribe) encode(e *bitflux.EncBE, version byte) byte {
+	if len(p.Subscriptions) == 0 {
+		e.Err = fmt.Errorf("%w: SUBSCRIBE without subscriptions", ErrProtocol)
+		return 0
+	}
+	encodePacketID(e, p.PacketID)
+	encodeProperties(e, p.Properties, TypeSubscribe, version)
+	for i := range p.Subscriptions {
+		s := &p.Subscriptions[i]
+		if e.Err != nil {
+			break
+		}
+		if e.Err = checkFilter(s.Filter); e.Err != nil {
+			break
+		}
+		o, err := s.options(version)
+		if err != nil {
+			e.Err = err
+			break
+		}
+		EncodeString(e, s.Filter)
+		e.U8(o)
+	}
+	return fixedFlags(TypeSubscribe)
+}
+
+func (p *Subscribe) decode(d *bitflux.DecBE, h header) {
+	p.PacketID = decodePacketID(d)
+	p.Properties = decodeProperties(d, TypeSubscribe, h.version)
+	for d.Err == nil && d.N < h.size {
+		s := Subscription{Filter: DecodeString(d)}
+		o := d.U8()
+		if d.Err != nil {
+			return
+		}
+		if d.Err = checkFilter(s.Filter); d.Err != nil {
+			return
+		}
+		if d.Err = s.setOptions(o, h.version); d.Err != nil {
+			return
+		}
+		p.Subscriptions = append(p.Subscriptions, s)
+	}
+	if d.Err == nil && len(p.Subscriptions) == 0 {
+		d.Err = fmt.Errorf("%w: SUBSCRIBE without subscriptions", ErrProtocol)
+	}
+}
+
+// checkSubackCodes returns an error for reason codes that MQTT 3.1.1 does not define.
+func checkSubackCodes(codes []ReasonCode, version byte) error {
+	if version == Version5 {
+		return nil
+	}
+	for _, rc := range codes {
+		if rc > GrantedQoS2 && rc != UnspecifiedError {
+			return fmt.Errorf("%w: SUBACK return code %#x", ErrProtocol, uint8(rc))
+		}
+	}
+	return nil
+}
+
+// Suback reports the result of each subscription of a SUBSCRIBE packet.
+type Suback struct {
+	PacketID    uint16
+	Properties  Properties
+	ReasonCodes []ReasonCode // One per subscription, in order
+}
+
+func (p *Suback) Type() PacketType { return TypeSuback }
+
+func (p *Suback) encode(e *bitflux.EncBE, version byte) byte {
+	if e.Err = checkSubackCodes(p.ReasonCodes, version); e.Err != nil {
+		return 0
+	}
+	encodePacketID(e, p.PacketID)
+	encodeProperties(e, p.Properties, TypeSuback, version)
+	for _, rc := range p.ReasonCodes {
+		e.U8(uint8(rc))
+	}
+	return 0
+}
+
+func (p *Suback) decode(d *bitflux.DecBE, h header) {
+	p.PacketID = decodePacketID(d)
+	p.Properties = decodeProperties(d, TypeSuback, h.version)
+	p.ReasonCodes = decodeReasonCodes(d, h)
+	if d.Err == nil {
+		d.Err = checkSubackCodes(p.ReasonCodes, h.version)
+	}
+}
+
+// decodeReasonCodes reads the reason codes that make up the rest of the packet.
+func decodeReasonCodes(d *bitflux.DecBE, h header) []ReasonCode {
+	b := d.Bytes(int(h.size - d.N))
+	if d.Err != nil || len(b) == 0 {
+		return nil
+	}
+	codes := make([]ReasonCode, len(b))
+	for i, c := range b {
+		codes[i] = ReasonCode(c)
+	}
+	return codes
+}
+
+// Unsubscribe removes one or more subscriptions.
+type Unsubscribe struct {
+	PacketID   uint16
+	Properties Properties
+	Filters    []string // At least one
+}
+
+func (p *Unsubscribe) Type() PacketType { return TypeUnsubscribe }
+
+func (p *Unsubscribe) encode(e *bitflux.EncBE, version byte) byte {
+	if len(p.Filters) == 0 {
+		e.Err = fmt.Errorf("%w: UNSUBSCRIBE without topic filters", ErrProtocol)
+		return 0
+	}
+	encodePacketID(e, p.PacketID)
+	encodeProperties(e, p.Properties, TypeUnsubscribe, version)
+	for _, f := range p.Filters {
+		if e.Err == nil {
+			e.Err = checkFilter(f)
+		}
+		EncodeString(e, f)
+	}
+	return fixedFlags(TypeUnsubscribe)
+}
+
+func (p *Unsubscribe) decode(d *bitflux.DecBE, h header) {
+	p.PacketID = decodePacketID(d)
+	p.Properties = decodeProperties(d, TypeUnsubscribe, h.version)
+	for d.Err == nil && d.N < h.size {
+		f := DecodeString(d)
+		if d.Err == nil {
+			d.Err = checkFilter(f)
+		}
+		p.Filters = append(p.Filters, f)
+	}
+	if d.Err == nil && len(p.Filters) == 0 {
+		d.Err = fmt.Errorf("%w: UNSUBSCRIBE without topic filters", ErrProtocol)
+	}
+}
+
+// Unsuback acknowledges an UNSUBSCRIBE packet.
+type Unsuback struct {
+	PacketID    uint16
+	Properties  Properties   // MQTT 5.0 only
+	ReasonCodes []ReasonCode // MQTT 5.0 only, one per topic filter, in order
+}
+
+func (p *Unsuback) Type() PacketType { return TypeUnsuback }
+
+func (p *Unsuback) encode(e *bitflux.EncBE, version byte) byte {
+	if version != Version5 && len(p.ReasonCodes) > 0 {
+		e.Err = fmt.Errorf("%w: reason codes in MQTT 3.1.1 UNSUBACK", ErrProtocol)
+		return 0
+	}
+	encodePacketID(e, p.PacketID)
+	encodeProperties(e, p.Properties, TypeUnsuback, version)
+	for _, rc := range p.ReasonCodes {
+		e.U8(uint8(rc))
+	}
+	return 0
+}
+
+func (p *Unsuback) decode(d *bitflux.DecBE, h header) {
+	p.PacketID = decodePacketID(d)
+	if h.version == Version5 {
+		p.Properties = decodeProperties(d, TypeUnsuback, h.version)
+		p.ReasonCodes = decodeReasonCodes(d, h)
+	}
+}
+
+// Pingreq is sent by clients to keep the connection alive.
+type Pingreq struct{}
+
+func (p *Pingreq) Type() PacketType                     { return TypePingreq }
+func (p *Pingreq) encode(e *bitflux.EncBE, _ byte) byte { return 0 }
+func (p *Pingreq) decode(d *bitflux.DecBE, _ header)    {}
+
+// Pingresp answers a PINGREQ packet.
+type Pingresp struct{}
+
+func (p *Pingresp) Type() PacketType                     { return TypePingresp }
+func (p *Pingresp) encode(e *bitflux.EncBE, _ byte) byte { return 0 }
+func (p *Pingresp) decode(d *bitflux.DecBE, _ header)    {}
+
+// Disconnect closes the connection. Servers only send it in MQTT 5.0.
+type Disconnect struct {
+	ReasonCode ReasonCode // MQTT 5.0 only
+	Properties Properties // MQTT 5.0 only
+}
+
+func (p *Disconnect) Type() PacketType { return TypeDisconnect }
+
+func (p *Disconnect) encode(e *bitflux.EncBE, version byte) byte {
+	encodeReason(e, p.ReasonCode, p.Properties, TypeDisconnect, version)
+	return 0
+}
+
+func (p *Disconnect) decode(d *bitflux.DecBE, h header) {
+	p.ReasonCode, p.Properties = decodeReason(d, h, TypeDisconnect)
+}
+
+// Auth carries an MQTT 5.0 extended authentication exchange.
+type Auth struct {
+	ReasonCode ReasonCode
+	Properties Properties
+}
+
+func (p *Auth) Type() PacketType { return TypeAuth }
+
+func (p *Auth) encode(e *bitflux.EncBE, version byte) byte {
+	encodeReason(e, p.ReasonCode, p.Properties, TypeAuth, version)
+	return 0
+}
+
+func (p *Auth) decode(d *bitflux.DecBE, h header) {
+	p.ReasonCode, p.Properties = decodeReason(d, h, TypeAuth)
+}
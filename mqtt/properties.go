@@ -0,0 +1,302 @@
+package mqtt
+
+import (
+	"fmt"
+
+	"github.com/jon-ski/bitflux"
+)
+
+// PropertyID identifies an MQTT 5.0 property.
+type PropertyID uint8
+
+// MQTT 5.0 properties.
+const (
+	PayloadFormatIndicator          PropertyID = 0x01
+	MessageExpiryInterval           PropertyID = 0x02
+	ContentType                     PropertyID = 0x03
+	ResponseTopic                   PropertyID = 0x08
+	CorrelationData                 PropertyID = 0x09
+	SubscriptionIdentifier          PropertyID = 0x0B
+	SessionExpiryInterval           PropertyID = 0x11
+	AssignedClientIdentifier        PropertyID = 0x12
+	ServerKeepAlive                 PropertyID = 0x13
+	AuthenticationMethod            PropertyID = 0x15
+	AuthenticationData              PropertyID = 0x16
+	RequestProblemInformation       PropertyID = 0x17
+	WillDelayInterval               PropertyID = 0x18
+	RequestResponseInformation      PropertyID = 0x19
+	ResponseInformation             PropertyID = 0x1A
+	ServerReference                 PropertyID = 0x1C
+	ReasonString                    PropertyID = 0x1F
+	ReceiveMaximum                  PropertyID = 0x21
+	TopicAliasMaximum               PropertyID = 0x22
+	TopicAlias                      PropertyID = 0x23
+	MaximumQoS                      PropertyID = 0x24
+	RetainAvailable                 PropertyID = 0x25
+	UserProperty                    PropertyID = 0x26
+	MaximumPacketSize               PropertyID = 0x27
+	WildcardSubscriptionAvailable   PropertyID = 0x28
+	SubscriptionIdentifierAvailable PropertyID = 0x29
+	SharedSubscriptionAvailable     PropertyID = 0x2A
+)
+
+var propertyIDs = bitflux.NewEnum("PropertyID", 1, map[PropertyID]string{
+	PayloadFormatIndicator:          "PayloadFormatIndicator",
+	MessageExpiryInterval:           "MessageExpiryInterval",
+	ContentType:                     "ContentType",
+	ResponseTopic:                   "ResponseTopic",
+	CorrelationData:                 "CorrelationData",
+	SubscriptionIdentifier:          "SubscriptionIdentifier",
+	SessionExpiryInterval:           "SessionExpiryInterval",
+	AssignedClientIdentifier:        "AssignedClientIdentifier",
+	ServerKeepAlive:                 "ServerKeepAlive",
+	AuthenticationMethod:            "AuthenticationMethod",
+	AuthenticationData:              "AuthenticationData",
+	RequestProblemInformation:       "RequestProblemInformation",
+	WillDelayInterval:               "WillDelayInterval",
+	RequestResponseInformation:      "RequestResponseInformation",
+	ResponseInformation:             "ResponseInformation",
+	ServerReference:                 "ServerReference",
+	ReasonString:                    "ReasonString",
+	ReceiveMaximum:                  "ReceiveMaximum",
+	TopicAliasMaximum:               "TopicAliasMaximum",
+	TopicAlias:                      "TopicAlias",
+	MaximumQoS:                      "MaximumQoS",
+	RetainAvailable:                 "RetainAvailable",
+	UserProperty:                    "UserProperty",
+	MaximumPacketSize:               "MaximumPacketSize",
+	WildcardSubscriptionAvailable:   "WildcardSubscriptionAvailable",
+	SubscriptionIdentifierAvailable: "SubscriptionIdentifierAvailable",
+	SharedSubscriptionAvailable:     "SharedSubscriptionAvailable",
+})
+
+func (id PropertyID) String() string { return propertyIDs.String(id) }
+
+// Property is an MQTT 5.0 property. Only the fields for the type of its ID are used.
+type Property struct {
+	ID    PropertyID
+	Int   uint32 // Byte, Two Byte, Four Byte and Variable Byte Integer properties
+	Str   string // UTF-8 Encoded String properties, and the name of user properties
+	Value string // Value of user properties
+	Data  []byte // Binary Data properties
+}
+
+// Properties is the list of properties of a packet, in encoding order.
+type Properties []Property
+
+// Get returns the first property with the given ID.
+func (ps Properties) Get(id PropertyID) (Property, bool) {
+	for _, p := range ps {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// propertyType is the encoding of a property's value.
+type propertyType uint8
+
+const (
+	propByte propertyType = iota
+	propU16
+	propU32
+	propVarint
+	propString
+	propBinary
+	propPair
+)
+
+// willProperties stands for the will properties of a CONNECT packet in the allowed sets of properties.
+const willProperties PacketType = 0
+
+// properties lists the type of every property and the packets it may appear in.
+var properties = map[PropertyID]struct {
+	typ     propertyType
+	allowed uint16 // Bit set of the packet types, willProperties included
+}{
+	PayloadFormatIndicator:          {propByte, in(TypePublish, willProperties)},
+	MessageExpiryInterval:           {propU32, in(TypePublish, willProperties)},
+	ContentType:                     {propString, in(TypePublish, willProperties)},
+	ResponseTopic:                   {propString, in(TypePublish, willProperties)},
+	CorrelationData:                 {propBinary, in(TypePublish, willProperties)},
+	SubscriptionIdentifier:          {propVarint, in(TypePublish, TypeSubscribe)},
+	SessionExpiryInterval:           {propU32, in(TypeConnect, TypeConnack, TypeDisconnect)},
+	AssignedClientIdentifier:        {propString, in(TypeConnack)},
+	ServerKeepAlive:                 {propU16, in(TypeConnack)},
+	AuthenticationMethod:            {propString, in(TypeConnect, TypeConnack, TypeAuth)},
+	AuthenticationData:              {propBinary, in(TypeConnect, TypeConnack, TypeAuth)},
+	RequestProblemInformation:       {propByte, in(TypeConnect)},
+	WillDelayInterval:               {propU32, in(willProperties)},
+	RequestResponseInformation:      {propByte, in(TypeConnect)},
+	ResponseInformation:             {propString, in(TypeConnack)},
+	ServerReference:                 {propString, in(TypeConnack, TypeDisconnect)},
+	ReasonString:                    {propString, in(TypeConnack, TypePuback, TypePubrec, TypePubrel, TypePubcomp, TypeSuback, TypeUnsuback, TypeDisconnect, TypeAuth)},
+	ReceiveMaximum:                  {propU16, in(TypeConnect, TypeConnack)},
+	TopicAliasMaximum:               {propU16, in(TypeConnect, TypeConnack)},
+	TopicAlias:                      {propU16, in(TypePublish)},
+	MaximumQoS:                      {propByte, in(TypeConnack)},
+	RetainAvailable:                 {propByte, in(TypeConnack)},
+	UserProperty:                    {propPair, 0xFFFF},
+	MaximumPacketSize:               {propU32, in(TypeConnect, TypeConnack)},
+	WildcardSubscriptionAvailable:   {propByte, in(TypeConnack)},
+	SubscriptionIdentifierAvailable: {propByte, in(TypeConnack)},
+	SharedSubscriptionAvailable:     {propByte, in(TypeConnack)},
+}
+
+func in(types ...PacketType) uint16 {
+	var set uint16
+	for _, t := range types {
+		set |= 1 << t
+	}
+	return set
+}
+
+// where names the packet properties belong to in errors.
+func where(t PacketType) string {
+	if t == willProperties {
+		return "will properties"
+	}
+	return t.String()
+}
+
+// check returns an error if the properties cannot be encoded or are not allowed in packets of type t.
+func (ps Properties) check(t PacketType) error {
+	var seen uint64
+	for _, p := range ps {
+		prop, ok := properties[p.ID]
+		if !ok {
+			return fmt.Errorf("%w: unknown property %#02x", ErrMalformed, uint8(p.ID))
+		}
+		if prop.allowed&(1<<t) == 0 {
+			return fmt.Errorf("%w: property %v in %s", ErrProtocol, p.ID, where(t))
+		}
+		if p.ID != UserProperty && !(p.ID == SubscriptionIdentifier && t == TypePublish) {
+			if seen&(1<<p.ID) != 0 {
+				return fmt.Errorf("%w: duplicate property %v", ErrProtocol, p.ID)
+			}
+			seen |= 1 << p.ID
+		}
+		switch {
+		case prop.typ == propByte && p.Int > 0xFF,
+			prop.typ == propU16 && p.Int > 0xFFFF,
+			prop.typ == propVarint && (p.Int > MaxVarint || p.Int == 0 && p.ID == SubscriptionIdentifier):
+			return fmt.Errorf("%w: property %v value %d", ErrProtocol, p.ID, p.Int)
+		}
+	}
+	return nil
+}
+
+// size returns the encoded size of the properties, without their length.
+func (ps Properties) size() int {
+	n := 0
+	for _, p := range ps {
+		n++
+		switch properties[p.ID].typ {
+		case propByte:
+			n++
+		case propU16:
+			n += 2
+		case propU32:
+			n += 4
+		case propVarint:
+			n += varintLen(p.Int)
+		case propString:
+			n += 2 + len(p.Str)
+		case propBinary:
+			n += 2 + len(p.Data)
+		case propPair:
+			n += 4 + len(p.Str) + len(p.Value)
+		}
+	}
+	return n
+}
+
+// encodeProperties writes the property length and ps to e for a packet of type t.
+// In MQTT 3.1.1 packets, which have no properties, it writes nothing and sets
+// e.Err to an error wrapping ErrProtocol if ps is not empty.
+func encodeProperties(e *bitflux.EncBE, ps Properties, t PacketType, version byte) {
+	if e.Err != nil {
+		return
+	}
+	if version != Version5 {
+		if len(ps) > 0 {
+			e.Err = fmt.Errorf("%w: properties in MQTT 3.1.1 %s", ErrProtocol, where(t))
+		}
+		return
+	}
+	if e.Err = ps.check(t); e.Err != nil {
+		return
+	}
+	EncodeVarint(e, uint32(ps.size()))
+	for _, p := range ps {
+		e.U8(uint8(p.ID))
+		switch properties[p.ID].typ {
+		case propByte:
+			e.U8(uint8(p.Int))
+		case propU16:
+			e.U16(uint16(p.Int))
+		case propU32:
+			e.U32(p.Int)
+		case propVarint:
+			EncodeVarint(e, p.Int)
+		case propString:
+			EncodeString(e, p.Str)
+		case propBinary:
+			EncodeBinary(e, p.Data)
+		case propPair:
+			EncodeString(e, p.Str)
+			EncodeString(e, p.Value)
+		}
+	}
+}
+
+// decodeProperties reads the property length and the properties of a packet of type t from d.
+// MQTT 3.1.1 packets have no properties.
+func decodeProperties(d *bitflux.DecBE, t PacketType, version byte) Properties {
+	if version != Version5 {
+		return nil
+	}
+	n := DecodeVarint(d)
+	if d.Err != nil || n == 0 {
+		return nil
+	}
+	var ps Properties
+	end := d.N + int64(n)
+	for d.N < end && d.Err == nil {
+		p := Property{ID: PropertyID(d.U8())}
+		prop, ok := properties[p.ID]
+		if !ok && d.Err == nil {
+			d.Err = fmt.Errorf("%w: unknown property %#02x", ErrMalformed, uint8(p.ID))
+		}
+		switch prop.typ {
+		case propByte:
+			p.Int = uint32(d.U8())
+		case propU16:
+			p.Int = uint32(d.U16())
+		case propU32:
+			p.Int = d.U32()
+		case propVarint:
+			p.Int = DecodeVarint(d)
+		case propString:
+			p.Str = DecodeString(d)
+		case propBinary:
+			p.Data = DecodeBinary(d)
+		case propPair:
+			p.Str = DecodeString(d)
+			p.Value = DecodeString(d)
+		}
+		ps = append(ps, p)
+	}
+	if d.Err != nil {
+		return nil
+	}
+	if d.N != end {
+		d.Err = fmt.Errorf("%w: properties overrun their length", ErrMalformed)
+		return nil
+	}
+	if d.Err = ps.check(t); d.Err != nil {
+		return nil
+	}
+	return ps
+}
@@ -0,0 +1,74 @@
+package bitflux
+
+import "io"
+
+// lookahead holds bytes that were read from a decoder's reader by Peek but not
+// consumed yet, and the bytes consumed since Mark. Decoders take from it before
+// reading from their reader.
+type lookahead struct {
+	ahead []byte // unconsumed bytes, a window into buf
+	buf   []byte // storage for ahead
+
+	marking  bool   // Mark is active
+	seekPos  int64  // reader offset of the mark, or -1 when buffering
+	kept     []byte // bytes consumed since the mark when buffering
+	limit    int    // limit on len(kept), negative means no limit
+	markN    int64  // decoder N at the mark
+	markBase int64  // decoder Base at the mark
+}
+
+// reset drops the buffered bytes and any mark.
+func (l *lookahead) reset() {
+	l.ahead = nil
+	l.marking = false
+	l.kept = l.kept[:0]
+}
+
+// fill reads from r until at least n bytes are buffered.
+func (l *lookahead) fill(r io.Reader, n int) error {
+	if len(l.ahead) >= n {
+		return nil
+	}
+	if cap(l.ahead) < n {
+		if len(l.buf) < n {
+			l.buf = make([]byte, max(n, 64))
+		}
+		l.ahead = l.buf[:copy(l.buf, l.ahead)]
+	}
+	m := len(l.ahead)
+	k, err := io.ReadFull(r, l.ahead[m:n])
+	l.ahead = l.ahead[:m+k]
+	if err == io.EOF && m > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// take copies buffered bytes into p and returns how many were copied.
+func (l *lookahead) take(p []byte) int {
+	n := copy(p, l.ahead)
+	l.ahead = l.ahead[n:]
+	return n
+}
+
+// reader returns r, preceded by the buffered bytes if there are any.
+// While a buffering mark is active, the bytes read are kept for Rewind.
+func (l *lookahead) reader(r io.Reader) io.Reader {
+	if len(l.ahead) > 0 {
+		r = io.MultiReader(aheadReader{l}, r)
+	}
+	if l.buffering() {
+		r = io.TeeReader(r, keepWriter{l})
+	}
+	return r
+}
+
+// aheadReader reads the buffered bytes of a lookahead.
+type aheadReader struct{ l *lookahead }
+
+func (a aheadReader) Read(p []byte) (int, error) {
+	if len(a.l.ahead) == 0 {
+		return 0, io.EOF
+	}
+	return a.l.take(p), nil
+}
@@ -0,0 +1,73 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestPeek(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	dec := NewDecBE(iotest.OneByteReader(bytes.NewReader(data)))
+
+	if v := dec.PeekU8(); v != 0x01 {
+		t.Fatalf("PeekU8: got=%#x", v)
+	}
+	if v := dec.PeekU16(); v != 0x0102 {
+		t.Fatalf("PeekU16: got=%#x", v)
+	}
+	if v := dec.PeekU32(); v != 0x01020304 {
+		t.Fatalf("PeekU32: got=%#x", v)
+	}
+	if dec.N != 0 {
+		t.Fatalf("N after peeks: got=%d, want=0", dec.N)
+	}
+	if v := dec.U16(); v != 0x0102 {
+		t.Fatalf("U16: got=%#x", v)
+	}
+	if !bytes.Equal(dec.Peek(3), []byte{0x03, 0x04, 0x05}) {
+		t.Fatalf("Peek after read: got=% x", dec.Peek(3))
+	}
+	if v := dec.U32(); v != 0x03040506 || dec.N != 6 {
+		t.Fatalf("U32: got=%#x N=%d", v, dec.N)
+	}
+	if dec.Err != nil {
+		t.Fatalf("unexpected error: %v", dec.Err)
+	}
+	if dec.Peek(1) != nil || dec.Err != io.EOF {
+		t.Fatalf("Peek at end: err=%v, want EOF", dec.Err)
+	}
+}
+
+func TestPeekDispatch(t *testing.T) {
+	// A type byte selects the layout, the message is then decoded from its start.
+	dec := NewDecLE(bytes.NewReader([]byte{0x02, 0x34, 0x12, 0xAA, 0xBB}))
+	switch dec.PeekU8() {
+	case 0x02:
+		if typ, v := dec.U8(), dec.U16(); typ != 2 || v != 0x1234 {
+			t.Fatalf("got type=%d v=%#x", typ, v)
+		}
+	default:
+		t.Fatal("unexpected type")
+	}
+	dec.Peek(1)
+	if rest := dec.ReadAll(); !bytes.Equal(rest, []byte{0xAA, 0xBB}) || dec.N != 5 {
+		t.Fatalf("ReadAll: got=% x N=%d", rest, dec.N)
+	}
+}
+
+func TestPeekShort(t *testing.T) {
+	dec := NewDecLE(bytes.NewReader([]byte{0x01}))
+	if v := dec.PeekU16(); v != 0 || !errors.Is(dec.Err, io.ErrUnexpectedEOF) {
+		t.Fatalf("got=%d err=%v, want ErrUnexpectedEOF", v, dec.Err)
+	}
+
+	dec = NewDecLE(bytes.NewReader([]byte{0x01, 0x02, 0x03}))
+	dec.Peek(2)
+	dec.U32()
+	if !errors.Is(dec.Err, io.ErrUnexpectedEOF) || dec.N != 3 {
+		t.Fatalf("got err=%v N=%d, want ErrUnexpectedEOF after 3 bytes", dec.Err, dec.N)
+	}
+}
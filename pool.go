@@ -0,0 +1,69 @@
+package bitflux
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the largest buffer capacity kept when an encoder is released,
+// so a single large message does not pin memory in the pool.
+const maxPooledBuffer = 64 * 1024
+
+// BufEncLE is a little-endian encoder writing into its own reusable buffer.
+// Obtain one with GetEncLE and call Release once the encoded bytes are no longer used.
+type BufEncLE struct {
+	EncLE
+	Buf bytes.Buffer // Backing buffer the encoder writes to
+}
+
+// BufEncBE is a big-endian encoder writing into its own reusable buffer.
+// Obtain one with GetEncBE and call Release once the encoded bytes are no longer used.
+type BufEncBE struct {
+	EncBE
+	Buf bytes.Buffer // Backing buffer the encoder writes to
+}
+
+var (
+	encLEPool = sync.Pool{New: func() any { return new(BufEncLE) }}
+	encBEPool = sync.Pool{New: func() any { return new(BufEncBE) }}
+)
+
+// GetEncLE returns an empty little-endian encoder from a pool.
+func GetEncLE() *BufEncLE {
+	e := encLEPool.Get().(*BufEncLE)
+	e.Buf.Reset()
+	e.Reset(&e.Buf)
+	e.Fill = 0
+	return e
+}
+
+// Bytes returns the encoded bytes. They are only valid until the encoder is released.
+func (e *BufEncLE) Bytes() []byte { return e.Buf.Bytes() }
+
+// Release returns the encoder to the pool. It must not be used afterwards.
+func (e *BufEncLE) Release() {
+	if e.Buf.Cap() > maxPooledBuffer {
+		return
+	}
+	encLEPool.Put(e)
+}
+
+// GetEncBE returns an empty big-endian encoder from a pool.
+func GetEncBE() *BufEncBE {
+	e := encBEPool.Get().(*BufEncBE)
+	e.Buf.Reset()
+	e.Reset(&e.Buf)
+	e.Fill = 0
+	return e
+}
+
+// Bytes returns the encoded bytes. They are only valid until the encoder is released.
+func (e *BufEncBE) Bytes() []byte { return e.Buf.Bytes() }
+
+// Release returns the encoder to the pool. It must not be used afterwards.
+func (e *BufEncBE) Release() {
+	if e.Buf.Cap() > maxPooledBuffer {
+		return
+	}
+	encBEPool.Put(e)
+}
@@ -0,0 +1,115 @@
+package bitflux
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+var (
+	testMessageWords   = []uint16{1, 2, 3}
+	testMessagePayload = []byte("payload")
+)
+
+func encodeTestMessage(e *EncBE) {
+	e.U16(0x0102)
+	e.U32(0x03040506)
+	e.F64(1.5)
+	e.U16s(testMessageWords)
+	e.Align(8)
+	e.Write(testMessagePayload)
+}
+
+func TestPooledEncBE(t *testing.T) {
+	e := GetEncBE()
+	e.U16(0xABCD)
+	e.Err = io.ErrShortWrite // leave state behind for the next user
+	e.Release()
+
+	e = GetEncBE()
+	defer e.Release()
+	if e.Err != nil || e.N != 0 || len(e.Bytes()) != 0 {
+		t.Fatalf("pooled encoder not reset: err=%v n=%d len=%d", e.Err, e.N, len(e.Bytes()))
+	}
+	encodeTestMessage(&e.EncBE)
+
+	var want bytes.Buffer
+	encodeTestMessage(NewEncBE(&want))
+	if !bytes.Equal(e.Bytes(), want.Bytes()) {
+		t.Fatalf("got=% x, want=% x", e.Bytes(), want.Bytes())
+	}
+}
+
+func TestDecReset(t *testing.T) {
+	dec := NewDecLE(bytes.NewReader([]byte{0x01}))
+	dec.U16()
+	if dec.Err == nil {
+		t.Fatal("expected error")
+	}
+	dec.Reset(bytes.NewReader([]byte{0x34, 0x12}))
+	if v := dec.U16(); v != 0x1234 || dec.Err != nil || dec.N != 2 {
+		t.Fatalf("got=%#x err=%v n=%d", v, dec.Err, dec.N)
+	}
+}
+
+func TestPooledEncZeroAllocs(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		e := GetEncBE()
+		encodeTestMessage(&e.EncBE)
+		_ = e.Bytes()
+		e.Release()
+	})
+	if allocs != 0 {
+		t.Fatalf("got %v allocs per message, want 0", allocs)
+	}
+}
+
+func BenchmarkPooledEncBE(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := GetEncBE()
+		encodeTestMessage(&e.EncBE)
+		_ = e.Bytes()
+		e.Release()
+	}
+}
+
+func BenchmarkPooledEncLE(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := GetEncLE()
+		e.U16(0x0102)
+		e.U32(0x03040506)
+		e.F64(1.5)
+		_ = e.Bytes()
+		e.Release()
+	}
+}
+
+func BenchmarkNewEncBEWithCapacity(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		e := NewEncBEWithCapacity(64)
+		encodeTestMessage(e)
+	}
+}
+
+func BenchmarkDecBEReset(b *testing.B) {
+	var buf bytes.Buffer
+	encodeTestMessage(NewEncBE(&buf))
+	data := buf.Bytes()
+	r := bytes.NewReader(data)
+	dec := NewDecBE(r)
+	v := make([]uint16, 3)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.Reset(data)
+		dec.Reset(r)
+		dec.U16()
+		dec.U32()
+		dec.F64()
+		dec.U16s(v)
+		dec.Align(8)
+		dec.Skip(7)
+	}
+}
@@ -0,0 +1,155 @@
+package bitflux
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoHandler is reported when a router decodes a message that has no handler.
+var ErrNoHandler = errors.New("bitflux: no handler for message")
+
+// Handler handles a decoded message. Anything encoded to reply is sent back to the
+// client as one frame once the handler returns without error.
+type Handler func(ctx context.Context, msg any, reply *BufEncBE) error
+
+// Router reads length-prefixed frames from connections, decodes their payloads with a
+// Union and calls the handler registered for each message's tag. Handlers must be
+// registered before serving starts.
+type Router struct {
+	Union  *Union       // Message types, decoded big-endian from each frame's payload
+	Prefix LengthPrefix // Framing of requests and replies
+
+	// Warn, if set, is called with errors for single messages, such as unknown tags or
+	// failed handlers, and the message is skipped. Otherwise such errors end the connection.
+	Warn func(err error)
+
+	handlers map[uint64]Handler
+}
+
+// NewRouter creates a router for the messages of u, framed as described by f.
+func NewRouter(u *Union, f LengthPrefix) *Router {
+	return &Router{Union: u, Prefix: f, handlers: make(map[uint64]Handler)}
+}
+
+// Handle registers h for messages with the given tag. It panics if tag already has a handler.
+func (r *Router) Handle(tag uint64, h Handler) {
+	if _, dup := r.handlers[tag]; dup {
+		panic("bitflux: duplicate handler for tag " + strconv.FormatUint(tag, 10))
+	}
+	r.handlers[tag] = h
+}
+
+// HandleType registers h for the messages of type T, which must be registered in r.Union.
+func HandleType[T any](r *Router, h func(ctx context.Context, msg T, reply *BufEncBE) error) {
+	var zero T
+	tag, ok := r.Union.Tag(zero)
+	if !ok {
+		panic(fmt.Sprintf("bitflux: %T is not registered in the router's union", zero))
+	}
+	r.Handle(tag, func(ctx context.Context, msg any, reply *BufEncBE) error {
+		return h(ctx, msg.(T), reply)
+	})
+}
+
+// Serve accepts connections from ln and serves each of them in its own goroutine
+// until ctx is done. It then closes ln, lets the handlers that are running finish,
+// and returns nil once all connections are closed. If Accept fails for another
+// reason, Serve closes ln and returns the error after its connections are closed.
+func (r *Router) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			ln.Close()
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.ServeConn(ctx, conn); err != nil && r.Warn != nil {
+				r.Warn(err)
+			}
+		}()
+	}
+}
+
+// ServeConn serves the messages read from conn until the client closes it or ctx is
+// done, and closes conn. It returns nil in those cases and the error otherwise.
+func (r *Router) ServeConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	fr := NewLengthReader(conn, r.Prefix)
+	fw := NewLengthWriter(conn, r.Prefix)
+	var payload bytes.Reader
+	dec := NewDecBE(&payload)
+	for {
+		frame, err := fr.ReadFrame()
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("bitflux: %s: %w", conn.RemoteAddr(), err)
+		}
+		payload.Reset(frame)
+		dec.Reset(&payload)
+		if err := r.dispatch(ctx, dec, fw); err != nil {
+			err = fmt.Errorf("bitflux: %s: %w", conn.RemoteAddr(), err)
+			if fw.Err != nil || r.Warn == nil {
+				return err
+			}
+			r.Warn(err)
+		}
+	}
+}
+
+// dispatch decodes one message from dec, calls its handler and writes the reply to fw.
+func (r *Router) dispatch(ctx context.Context, dec *DecBE, fw *LengthWriter) error {
+	msg := r.Union.DecodeBE(dec)
+	if dec.Err != nil {
+		return dec.Err
+	}
+	tag, _ := r.Union.Tag(msg)
+	h, ok := r.handlers[tag]
+	if !ok {
+		return fmt.Errorf("%w: tag %d", ErrNoHandler, tag)
+	}
+	reply := GetEncBE()
+	defer reply.Release()
+	if err := h(ctx, msg, reply); err != nil {
+		return fmt.Errorf("handler for tag %d: %w", tag, err)
+	}
+	if reply.Err != nil {
+		return fmt.Errorf("reply for tag %d: %w", tag, reply.Err)
+	}
+	if reply.Buf.Len() == 0 {
+		return nil
+	}
+	return fw.WriteFrame(reply.Bytes())
+}
+
+// Pipe returns the client end of an in-memory connection served by r until the
+// client closes it or ctx is done. It allows testing handlers without a network.
+func (r *Router) Pipe(ctx context.Context) net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		if err := r.ServeConn(ctx, server); err != nil && r.Warn != nil {
+			r.Warn(err)
+		}
+	}()
+	return client
+}
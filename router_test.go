@@ -0,0 +1,175 @@
+package bitflux
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type routerEcho struct{ Text string }
+
+func (m *routerEcho) DecodeBE(d *DecBE) { m.Text = string(d.ReadAll()) }
+func (m *routerEcho) EncodeBE(e *EncBE) { e.Write([]byte(m.Text)) }
+
+type routerAdd struct{ A, B uint16 }
+
+func (m *routerAdd) DecodeBE(d *DecBE) { m.A, m.B = d.U16(), d.U16() }
+func (m *routerAdd) EncodeBE(e *EncBE) { e.U16(m.A); e.U16(m.B) }
+
+type routerSum struct{ Sum uint32 }
+
+func (m *routerSum) DecodeBE(d *DecBE) { m.Sum = d.U32() }
+func (m *routerSum) EncodeBE(e *EncBE) { e.U32(m.Sum) }
+
+var routerFraming = LengthPrefix{Width: 2, BigEndian: true}
+
+func newTestRouter() *Router {
+	u := NewUnion(1, true)
+	u.Register(1, func() any { return new(routerEcho) })
+	u.Register(2, func() any { return new(routerAdd) })
+	u.Register(3, func() any { return new(routerSum) })
+	r := NewRouter(u, routerFraming)
+	HandleType(r, func(ctx context.Context, m *routerEcho, reply *BufEncBE) error {
+		u.EncodeBE(&reply.EncBE, m)
+		return nil
+	})
+	HandleType(r, func(ctx context.Context, m *routerAdd, reply *BufEncBE) error {
+		u.EncodeBE(&reply.EncBE, &routerSum{uint32(m.A) + uint32(m.B)})
+		return nil
+	})
+	return r
+}
+
+// roundTrip sends msg over conn and decodes the reply.
+func roundTrip(t *testing.T, r *Router, conn net.Conn, msg any) any {
+	t.Helper()
+	enc := GetEncBE()
+	defer enc.Release()
+	r.Union.EncodeBE(&enc.EncBE, msg)
+	if err := NewLengthWriter(conn, routerFraming).WriteFrame(enc.Bytes()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	dec := NewLengthReader(conn, routerFraming).NextDecBE()
+	reply := r.Union.DecodeBE(dec)
+	if dec.Err != nil {
+		t.Fatalf("reply: %v", dec.Err)
+	}
+	return reply
+}
+
+func TestRouterLoopback(t *testing.T) {
+	r := newTestRouter()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("loopback unavailable: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Serve(ctx, ln) }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			defer conn.Close()
+			for j := 0; j < 10; j++ {
+				got := roundTrip(t, r, conn, &routerAdd{uint16(i), uint16(j)})
+				if s, ok := got.(*routerSum); !ok || s.Sum != uint32(i+j) {
+					t.Errorf("got=%#v, want sum %d", got, i+j)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// An idle connection must not keep Serve from returning.
+	idle, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer idle.Close()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after cancel")
+	}
+}
+
+func TestRouterPipe(t *testing.T) {
+	r := newTestRouter()
+	var mu sync.Mutex
+	var warnings []error
+	r.Warn = func(err error) {
+		mu.Lock()
+		warnings = append(warnings, err)
+		mu.Unlock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conn := r.Pipe(ctx)
+	defer conn.Close()
+
+	// A message without a handler is skipped and reported.
+	enc := GetEncBE()
+	r.Union.EncodeBE(&enc.EncBE, &routerSum{1})
+	if err := NewLengthWriter(conn, routerFraming).WriteFrame(enc.Bytes()); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	enc.Release()
+
+	if got, ok := roundTrip(t, r, conn, &routerEcho{"hello"}).(*routerEcho); !ok || got.Text != "hello" {
+		t.Fatalf("got=%#v", got)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(warnings) != 1 || !errors.Is(warnings[0], ErrNoHandler) {
+		t.Fatalf("warnings: got=%v", warnings)
+	}
+}
+
+func TestRouterGracefulShutdown(t *testing.T) {
+	r := newTestRouter()
+	started, release := make(chan struct{}), make(chan struct{})
+	r.Handle(3, func(ctx context.Context, msg any, reply *BufEncBE) error {
+		close(started)
+		<-release
+		reply.U8(0x01) // a reply without a tag, read as a raw frame below
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error)
+	go func() { done <- r.ServeConn(ctx, server) }()
+
+	enc := GetEncBE()
+	r.Union.EncodeBE(&enc.EncBE, &routerSum{1})
+	go NewLengthWriter(client, routerFraming).WriteFrame(enc.Bytes())
+	<-started
+	cancel()
+	close(release)
+
+	// The running handler finishes and its reply is delivered before the connection closes.
+	frame, err := NewLengthReader(client, routerFraming).ReadFrame()
+	if err != nil || len(frame) != 1 || frame[0] != 0x01 {
+		t.Fatalf("reply: got=% x err=%v", frame, err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("ServeConn: %v", err)
+	}
+	enc.Release()
+}
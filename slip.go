@@ -0,0 +1,142 @@
+package bitflux
+
+import (
+	"io"
+)
+
+// SLIP special characters (RFC 1055).
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+// SLIPWriter writes SLIP (RFC 1055) framed packets to an io.Writer.
+// It tracks the number of bytes written and any errors that occur during framing.
+type SLIPWriter struct {
+	W   io.Writer // The underlying writer to write frames to
+	N   int64     // Number of bytes written, including escapes and delimiters
+	Err error     // First error encountered during framing
+	buf []byte
+}
+
+// NewSLIPWriter creates a new SLIP frame writer that writes to the provided io.Writer.
+func NewSLIPWriter(w io.Writer) *SLIPWriter { return &SLIPWriter{W: w} }
+
+// WriteFrame escapes p and writes it as a single frame.
+// The frame is preceded by an END byte to flush any line noise, as recommended by RFC 1055.
+func (s *SLIPWriter) WriteFrame(p []byte) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.buf = append(s.buf[:0], slipEnd)
+	for _, c := range p {
+		switch c {
+		case slipEnd:
+			s.buf = append(s.buf, slipEsc, slipEscEnd)
+		case slipEsc:
+			s.buf = append(s.buf, slipEsc, slipEscEsc)
+		default:
+			s.buf = append(s.buf, c)
+		}
+	}
+	s.buf = append(s.buf, slipEnd)
+	n, err := s.W.Write(s.buf)
+	s.N += int64(n)
+	if err == nil && n < len(s.buf) {
+		err = io.ErrShortWrite
+	}
+	s.Err = err
+	return err
+}
+
+// Write implements io.Writer by writing p as a single frame.
+func (s *SLIPWriter) Write(p []byte) (int, error) {
+	if err := s.WriteFrame(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// SLIPReader reads SLIP (RFC 1055) framed packets from an io.Reader, one frame at a time.
+// Malformed and oversized frames are discarded and counted in Stats.
+type SLIPReader struct {
+	R        io.ByteReader // The underlying reader to read frames from
+	MaxFrame int           // Maximum decoded frame size; frames larger than this are dropped
+	Stats    FrameStats    // Frame statistics
+	Err      error         // First error encountered while reading
+	frame    []byte
+}
+
+// NewSLIPReader creates a new SLIP frame reader that reads from the provided io.Reader.
+// If r does not implement io.ByteReader it is wrapped in a bufio.Reader.
+func NewSLIPReader(r io.Reader) *SLIPReader {
+	return &SLIPReader{R: byteReader(r), MaxFrame: DefaultMaxFrame}
+}
+
+// ReadFrame returns the next non-empty decoded frame.
+// The returned slice is only valid until the next call to ReadFrame.
+// A partial frame at the end of the stream is counted as dropped, or as oversized if it
+// already exceeds MaxFrame, and io.ErrUnexpectedEOF is returned.
+func (s *SLIPReader) ReadFrame() ([]byte, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.frame = s.frame[:0]
+	var esc, bad, over bool
+	for {
+		c, err := s.R.ReadByte()
+		if err != nil {
+			if len(s.frame) > 0 || esc || bad || over {
+				if over {
+					s.Stats.Oversized++
+				} else {
+					s.Stats.Dropped++
+				}
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+			}
+			s.Err = err
+			return nil, err
+		}
+		if c == slipEnd {
+			switch {
+			case over:
+				s.Stats.Oversized++
+			case bad || esc:
+				s.Stats.Dropped++
+			case len(s.frame) > 0:
+				s.Stats.Frames++
+				return s.frame, nil
+			}
+			s.frame = s.frame[:0]
+			esc, bad, over = false, false, false
+			continue
+		}
+		if bad || over {
+			continue
+		}
+		if esc {
+			esc = false
+			switch c {
+			case slipEscEnd:
+				c = slipEnd
+			case slipEscEsc:
+				c = slipEsc
+			default:
+				bad = true
+				continue
+			}
+		} else if c == slipEsc {
+			esc = true
+			continue
+		}
+		if s.MaxFrame > 0 && len(s.frame) >= s.MaxFrame {
+			over = true
+			continue
+		}
+		s.frame = append(s.frame, c)
+	}
+}
@@ -0,0 +1,92 @@
+package bitflux
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSLIPWriteFrame(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSLIPWriter(&buf)
+	if err := w.WriteFrame([]byte{0x01, 0xC0, 0x02, 0xDB, 0x03}); err != nil {
+		t.Fatalf("WriteFrame error: %v", err)
+	}
+	expected := []byte{0xC0, 0x01, 0xDB, 0xDC, 0x02, 0xDB, 0xDD, 0x03, 0xC0}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("got=% x, want=% x", buf.Bytes(), expected)
+	}
+	if w.N != int64(len(expected)) {
+		t.Errorf("N: got=%d, want=%d", w.N, len(expected))
+	}
+}
+
+func TestSLIPRoundTrip(t *testing.T) {
+	frames := [][]byte{
+		{0x01, 0x02, 0x03},
+		{0xC0, 0xDB, 0xDC, 0xDD},
+		{0xFF},
+	}
+	var buf bytes.Buffer
+	w := NewSLIPWriter(&buf)
+	for _, f := range frames {
+		w.WriteFrame(f)
+	}
+	if w.Err != nil {
+		t.Fatalf("unexpected write error: %v", w.Err)
+	}
+
+	r := NewSLIPReader(&buf)
+	for i, want := range frames {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d: got=% x, want=% x", i, got, want)
+		}
+	}
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if r.Stats.Frames != int64(len(frames)) {
+		t.Errorf("Frames: got=%d, want=%d", r.Stats.Frames, len(frames))
+	}
+}
+
+func TestSLIPReaderDropsBadFrames(t *testing.T) {
+	stream := []byte{
+		0xC0, 0x01, 0xDB, 0x55, 0x02, 0xC0, // invalid escape
+		0x01, 0x02, 0x03, 0x04, 0x05, 0xC0, // oversized
+		0x0A, 0x0B, 0xC0, // good
+		0x0C, // truncated
+	}
+	r := NewSLIPReader(bytes.NewReader(stream))
+	r.MaxFrame = 4
+
+	got, err := r.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, []byte{0x0A, 0x0B}) {
+		t.Fatalf("got=% x, want=0a 0b", got)
+	}
+	if _, err := r.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	want := FrameStats{Frames: 1, Dropped: 2, Oversized: 1}
+	if r.Stats != want {
+		t.Errorf("Stats: got=%+v, want=%+v", r.Stats, want)
+	}
+
+	// An oversized frame cut off by the end of the stream is still oversized.
+	r = NewSLIPReader(bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04, 0x05}))
+	r.MaxFrame = 4
+	if _, err := r.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if want := (FrameStats{Oversized: 1}); r.Stats != want {
+		t.Errorf("Stats: got=%+v, want=%+v", r.Stats, want)
+	}
+}
@@ -0,0 +1,208 @@
+package bitflux
+
+import (
+	"io"
+)
+
+// SyncFunc inspects data beginning with a sync pattern match and decides whether it
+// starts a valid frame. It returns the length of the frame when data begins with a
+// valid frame, 0 or a length beyond data when more data is needed to decide, or a
+// negative value to reject the candidate.
+type SyncFunc func(data []byte) int
+
+// FixedSync returns a SyncFunc for frames of a fixed size that are accepted when valid
+// returns true, for example when a trailing checksum matches.
+func FixedSync(size int, valid func(frame []byte) bool) SyncFunc {
+	return func(data []byte) int {
+		if len(data) < size {
+			return 0
+		}
+		if valid == nil || valid(data[:size]) {
+			return size
+		}
+		return -1
+	}
+}
+
+// SyncScanner searches a stream for a sync pattern and returns the frames that follow it.
+// When a candidate frame is rejected the scanner slides forward one byte and searches again,
+// so decoding resynchronizes after a capture that starts mid-frame or after corrupted data.
+//
+// With BitAligned set the pattern is searched at every bit offset, most significant bit
+// first, and a rejected candidate slides forward one bit. Frames found at a bit offset are
+// shifted into whole bytes before Split sees them, and the scanner stays at that offset
+// until the pattern is found at another.
+type SyncScanner struct {
+	R          io.Reader  // The underlying reader to scan
+	Pattern    []byte     // Sync pattern every frame starts with
+	Mask       []byte     // Optional per-byte mask of significant pattern bits; nil compares all bits
+	BitAligned bool       // Search for Pattern at every bit offset rather than on byte boundaries
+	Split      SyncFunc   // Validates candidate frames and reports their length
+	MaxFrame   int        // Candidates longer than this, or still undecided at this size, are rejected
+	Stats      FrameStats // Frame statistics; Dropped counts rejected candidates
+	Discarded  int64      // Total number of bytes discarded while searching
+	Skipped    int        // Number of bytes discarded before the most recent frame
+	Err        error      // First error encountered while reading
+	buf        []byte
+	start      int
+	end        int
+	eof        bool
+	bit        int    // Bit offset of the scan position within buf[start]
+	aligned    []byte // Data from the scan position shifted into whole bytes when bit > 0
+}
+
+// NewSyncScanner creates a new scanner that searches r for pattern and validates candidates with split.
+func NewSyncScanner(r io.Reader, pattern []byte, split SyncFunc) *SyncScanner {
+	return &SyncScanner{R: r, Pattern: pattern, Split: split, MaxFrame: DefaultMaxFrame}
+}
+
+// match reports whether p, starting bit bits into p[0], starts with the sync pattern under the mask.
+func (s *SyncScanner) match(p []byte, bit int) bool {
+	for i, c := range s.Pattern {
+		x := p[i]
+		if bit > 0 {
+			x = x<<bit | p[i+1]>>(8-bit)
+		}
+		m := byte(0xFF)
+		if i < len(s.Mask) {
+			m = s.Mask[i]
+		}
+		if (x^c)&m != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// step returns the number of bits the search advances between candidate positions.
+func (s *SyncScanner) step() int {
+	if s.BitAligned {
+		return 1
+	}
+	return 8
+}
+
+// seek moves the scan position to bit p, counted from the start of buf[start],
+// and discards the whole bytes before it.
+func (s *SyncScanner) seek(p int) {
+	n := p / 8
+	s.start += n
+	s.Skipped += n
+	s.Discarded += int64(n)
+	if b := p % 8; b != s.bit {
+		s.bit = b
+		s.aligned = s.aligned[:0]
+	} else {
+		s.dropAligned(n)
+	}
+}
+
+// dropAligned drops n bytes from the front of the realigned data.
+func (s *SyncScanner) dropAligned(n int) {
+	if n >= len(s.aligned) {
+		s.aligned = s.aligned[:0]
+	} else {
+		s.aligned = s.aligned[n:]
+	}
+}
+
+// search moves to the next match of the pattern and reports whether one was found.
+// Without a match it moves to the first position that needs more data to test.
+func (s *SyncScanner) search() bool {
+	data := s.buf[s.start:s.end]
+	p := s.bit
+	for ; ; p += s.step() {
+		i, b := p/8, p%8
+		need := i + len(s.Pattern)
+		if b > 0 {
+			need++
+		}
+		if need > len(data) {
+			break
+		}
+		if s.match(data[i:], b) {
+			s.seek(p)
+			return true
+		}
+	}
+	s.seek(p)
+	return false
+}
+
+// data returns the buffered data from the scan position, shifted into whole bytes
+// when the position is at a bit offset.
+func (s *SyncScanner) data() []byte {
+	data := s.buf[s.start:s.end]
+	if s.bit == 0 {
+		return data
+	}
+	for i := len(s.aligned); i+1 < len(data); i++ {
+		s.aligned = append(s.aligned, data[i]<<s.bit|data[i+1]>>(8-s.bit))
+	}
+	return s.aligned
+}
+
+// fill reads more data into the buffer.
+func (s *SyncScanner) fill() {
+	if s.start > 0 {
+		s.end = copy(s.buf, s.buf[s.start:s.end])
+		s.start = 0
+	}
+	if s.end == len(s.buf) {
+		size := 2 * len(s.buf)
+		if size < 4096 {
+			size = 4096
+		}
+		buf := make([]byte, size)
+		copy(buf, s.buf[:s.end])
+		s.buf = buf
+	}
+	n, err := s.R.Read(s.buf[s.end:])
+	s.end += n
+	if err == io.EOF {
+		s.eof = true
+	} else if err != nil {
+		s.Err = err
+	}
+}
+
+// ReadFrame returns the next validated frame, beginning with the sync pattern.
+// The returned slice is only valid until the next call to ReadFrame.
+// At the end of the stream undecided candidates are rejected, so later frames in the
+// buffered data are still found, and io.EOF is returned once no candidate is left.
+func (s *SyncScanner) ReadFrame() ([]byte, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	s.Skipped = 0
+	for s.Err == nil {
+		if s.search() {
+			data := s.data()
+			n := s.Split(data)
+			switch {
+			case s.MaxFrame > 0 && (n > s.MaxFrame || n == 0 && len(data) >= s.MaxFrame):
+				n = -1
+			case s.eof && (n == 0 || n > len(data)):
+				n = -1
+			}
+			switch {
+			case n > 0 && n <= len(data):
+				s.start += n
+				s.dropAligned(n)
+				s.Stats.Frames++
+				return data[:n], nil
+			case n < 0:
+				s.Stats.Dropped++
+				s.seek(s.bit + s.step())
+				continue
+			}
+		}
+		if s.eof {
+			s.seek(8 * (s.end - s.start))
+			s.Err = io.EOF
+			break
+		}
+		s.fill()
+	}
+	return nil, s.Err
+}
@@ -0,0 +1,179 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// syncTestFrame builds a frame of sync word, length, payload and FCS-16.
+func syncTestFrame(payload ...byte) []byte {
+	f := append([]byte{0xEB, 0x90, byte(len(payload))}, payload...)
+	return FCS16.appendFCS(f, f)
+}
+
+func syncTestSplit(data []byte) int {
+	if len(data) < 3 {
+		return 0
+	}
+	n := 3 + int(data[2]) + 2
+	if len(data) < n {
+		return 0
+	}
+	if !bytes.Equal(FCS16.appendFCS(nil, data[:n-2]), data[n-2:n]) {
+		return -1
+	}
+	return n
+}
+
+func TestSyncScannerResync(t *testing.T) {
+	var stream []byte
+	stream = append(stream, 0x01, 0x02, 0xEB) // tail of a previous frame
+	stream = append(stream, syncTestFrame(0x11, 0x22)...)
+	stream = append(stream, 0xEB, 0x90, 0x05, 0x00) // false sync followed by garbage
+	stream = append(stream, syncTestFrame(0xEB, 0x90)...)
+
+	s := NewSyncScanner(iotest.HalfReader(bytes.NewReader(stream)), []byte{0xEB, 0x90}, syncTestSplit)
+	got, err := s.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := syncTestFrame(0x11, 0x22); !bytes.Equal(got, want) {
+		t.Fatalf("got=% x, want=% x", got, want)
+	}
+	if s.Skipped != 3 {
+		t.Errorf("Skipped: got=%d, want=3", s.Skipped)
+	}
+
+	got, err = s.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := syncTestFrame(0xEB, 0x90); !bytes.Equal(got, want) {
+		t.Fatalf("got=% x, want=% x", got, want)
+	}
+	if s.Skipped != 4 {
+		t.Errorf("Skipped: got=%d, want=4", s.Skipped)
+	}
+
+	if _, err := s.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if s.Discarded != 7 {
+		t.Errorf("Discarded: got=%d, want=7", s.Discarded)
+	}
+	if s.Stats.Frames != 2 || s.Stats.Dropped != 1 {
+		t.Errorf("Stats: got=%+v", s.Stats)
+	}
+}
+
+func TestSyncScannerTruncatedAtEOF(t *testing.T) {
+	// A candidate that would need more data than the stream holds is followed by a valid frame.
+	stream := append([]byte{0xEB, 0x90, 0x30}, syncTestFrame(0xAA)...)
+	s := NewSyncScanner(bytes.NewReader(stream), []byte{0xEB, 0x90}, syncTestSplit)
+	got, err := s.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := syncTestFrame(0xAA); !bytes.Equal(got, want) {
+		t.Fatalf("got=% x, want=% x", got, want)
+	}
+	if _, err := s.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if s.Discarded != 3 || s.Stats.Frames != 1 || s.Stats.Dropped != 1 {
+		t.Errorf("Discarded=%d Stats=%+v", s.Discarded, s.Stats)
+	}
+}
+
+func TestSyncScannerMask(t *testing.T) {
+	stream := []byte{0x00, 0xA3, 0x01, 0xA7, 0x02, 0x55, 0x03}
+	// Match any byte whose high nibble is 0xA.
+	s := NewSyncScanner(bytes.NewReader(stream), []byte{0xA0}, FixedSync(2, nil))
+	s.Mask = []byte{0xF0}
+	for _, want := range [][]byte{{0xA3, 0x01}, {0xA7, 0x02}} {
+		got, err := s.ReadFrame()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got=% x, want=% x", got, want)
+		}
+	}
+	if _, err := s.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if s.Discarded != 3 {
+		t.Errorf("Discarded: got=%d, want=3", s.Discarded)
+	}
+}
+
+// shiftBits returns p delayed by k bits, preceded by the low k bits of lead and padded with zero bits.
+func shiftBits(p []byte, k int, lead byte) []byte {
+	out := make([]byte, len(p)+1)
+	prev := lead
+	for i, c := range p {
+		out[i] = prev<<(8-k) | c>>k
+		prev = c
+	}
+	out[len(p)] = prev << (8 - k)
+	return out
+}
+
+func TestSyncScannerBitAligned(t *testing.T) {
+	// A frame 3 bits into the stream, then garbage and a frame 6 bits into the remaining bytes.
+	stream := shiftBits(append([]byte{0x13}, syncTestFrame(0x11, 0x22)...), 3, 0x05)
+	stream = append(stream, 0x00, 0x42)
+	stream = append(stream, shiftBits(syncTestFrame(0xEB, 0x90), 6, 0x2A)...)
+
+	s := NewSyncScanner(iotest.OneByteReader(bytes.NewReader(stream)), []byte{0xEB, 0x90}, syncTestSplit)
+	s.BitAligned = true
+	for _, want := range [][]byte{syncTestFrame(0x11, 0x22), syncTestFrame(0xEB, 0x90)} {
+		got, err := s.ReadFrame()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got=% x, want=% x", got, want)
+		}
+	}
+	if _, err := s.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if s.Stats.Frames != 2 {
+		t.Errorf("Stats: got=%+v", s.Stats)
+	}
+
+	// Without BitAligned only byte-aligned frames are found.
+	s = NewSyncScanner(bytes.NewReader(stream), []byte{0xEB, 0x90}, syncTestSplit)
+	if _, err := s.ReadFrame(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestSyncScannerMaxFrame(t *testing.T) {
+	// The first candidate announces a frame longer than MaxFrame; it must be rejected
+	// without reading further, which would fail here.
+	stream := append([]byte{0xEB, 0x90, 0xF0}, syncTestFrame(0x01)...)
+	r := io.MultiReader(bytes.NewReader(stream), iotest.ErrReader(errors.New("read past the frame")))
+	split := func(data []byte) int {
+		if len(data) >= 3 && len(data) < 3+int(data[2])+2 {
+			return 3 + int(data[2]) + 2 // announce the length before the data arrives
+		}
+		return syncTestSplit(data)
+	}
+	s := NewSyncScanner(r, []byte{0xEB, 0x90}, split)
+	s.MaxFrame = 16
+	got, err := s.ReadFrame()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := syncTestFrame(0x01); !bytes.Equal(got, want) {
+		t.Fatalf("got=% x, want=% x", got, want)
+	}
+	if s.Stats.Dropped != 1 || s.Skipped != 3 {
+		t.Errorf("Skipped=%d Stats=%+v", s.Skipped, s.Stats)
+	}
+}
@@ -0,0 +1,60 @@
+package bitflux
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNoTransaction is reported by Commit and Rollback when no transaction was begun.
+var ErrNoTransaction = errors.New("bitflux: commit or rollback without begin")
+
+// txState is the encoder state saved by Begin.
+type txState struct {
+	off  int   // start of the transaction in pending
+	n    int64 // encoder N at Begin
+	base int64 // encoder Base at Begin
+	err  error // encoder Err at Begin
+}
+
+// transaction holds the bytes written by an encoder while transactions are open.
+type transaction struct {
+	pending []byte    // bytes held back from the writer
+	txs     []txState // open transactions, innermost last
+}
+
+// reset drops any open transactions and their bytes.
+func (t *transaction) reset() {
+	t.pending = t.pending[:0]
+	t.txs = t.txs[:0]
+}
+
+// writer returns w, or a writer that holds bytes back while a transaction is open.
+func (t *transaction) writer(w io.Writer) io.Writer {
+	if len(t.txs) == 0 {
+		return w
+	}
+	return pendingWriter{t}
+}
+
+// pendingWriter appends to the pending bytes of a transaction.
+type pendingWriter struct{ t *transaction }
+
+func (w pendingWriter) Write(p []byte) (int, error) {
+	w.t.pending = append(w.t.pending, p...)
+	return len(p), nil
+}
+
+// writeAll writes p to w, handling partial writes. It returns the number of bytes written.
+func writeAll(w io.Writer, p []byte) (int, error) {
+	for off := 0; off < len(p); {
+		n, err := w.Write(p[off:])
+		off += n
+		if err != nil {
+			return off, err
+		}
+		if n == 0 { // defensive: writer made no progress
+			return off, io.ErrShortWrite
+		}
+	}
+	return len(p), nil
+}
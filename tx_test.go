@@ -0,0 +1,92 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// writeLog records each Write call.
+type writeLog struct{ writes [][]byte }
+
+func (w *writeLog) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// failMarshaler fails to marshal.
+type failMarshaler struct{}
+
+func (failMarshaler) MarshalBinary() ([]byte, error) { return nil, errors.New("marshal failed") }
+
+func TestTxCommit(t *testing.T) {
+	var w writeLog
+	enc := NewEncBE(&w)
+	enc.Begin()
+	enc.U16(0x0102)
+	enc.Begin()
+	enc.U8(0x03)
+	enc.Align(4)
+	enc.Commit()
+	if len(w.writes) != 0 {
+		t.Fatalf("bytes written before outer Commit: %v", w.writes)
+	}
+	if enc.N != 4 {
+		t.Fatalf("N inside transaction: got=%d, want=4", enc.N)
+	}
+	enc.Commit()
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	if len(w.writes) != 1 || !bytes.Equal(w.writes[0], []byte{0x01, 0x02, 0x03, 0x00}) {
+		t.Fatalf("writes: got=%v", w.writes)
+	}
+	enc.U8(0x04)
+	if len(w.writes) != 2 || enc.N != 5 {
+		t.Fatalf("write after Commit: writes=%d N=%d", len(w.writes), enc.N)
+	}
+}
+
+func TestTxErrorDiscards(t *testing.T) {
+	var w writeLog
+	enc := NewEncLE(&w)
+	enc.Begin()
+	enc.U32(1)
+	enc.MarkBase()
+	enc.Marshal(failMarshaler{})
+	enc.Commit()
+	if enc.Err == nil || len(w.writes) != 0 || enc.N != 0 || enc.Base != 0 {
+		t.Fatalf("err=%v writes=%v N=%d Base=%d", enc.Err, w.writes, enc.N, enc.Base)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncLE(&buf)
+	enc.U8(0xAA)
+	enc.Begin()
+	enc.U16(0x0102)
+	enc.Begin()
+	enc.MarkBase()
+	enc.U32(3)
+	enc.Marshal(failMarshaler{})
+	enc.Rollback()
+	if enc.Err != nil || enc.N != 3 || enc.Base != 0 {
+		t.Fatalf("after inner Rollback: err=%v N=%d Base=%d", enc.Err, enc.N, enc.Base)
+	}
+	enc.U8(0xBB)
+	enc.Commit()
+	if !bytes.Equal(buf.Bytes(), []byte{0xAA, 0x02, 0x01, 0xBB}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+
+	enc.Begin()
+	enc.U64(1)
+	enc.Rollback()
+	if buf.Len() != 4 || enc.N != 4 {
+		t.Fatalf("outer Rollback wrote: len=%d N=%d", buf.Len(), enc.N)
+	}
+	if enc.Commit(); !errors.Is(enc.Err, ErrNoTransaction) {
+		t.Fatalf("Commit without Begin: got err=%v", enc.Err)
+	}
+}
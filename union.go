@@ -0,0 +1,270 @@
+package bitflux
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	// ErrUnknownTag is reported when a decoded union tag has no registered type.
+	ErrUnknownTag = errors.New("bitflux: unknown union tag")
+	// ErrUnionType is reported for values whose type is not registered in a union,
+	// or that have no method to encode or decode them in the requested byte order.
+	ErrUnionType = errors.New("bitflux: unsupported union type")
+)
+
+// DecoderLE is implemented by types that decode themselves from a little-endian decoder.
+type DecoderLE interface{ DecodeLE(d *DecLE) }
+
+// DecoderBE is implemented by types that decode themselves from a big-endian decoder.
+type DecoderBE interface{ DecodeBE(d *DecBE) }
+
+// EncoderLE is implemented by types that encode themselves to a little-endian encoder.
+type EncoderLE interface{ EncodeLE(e *EncLE) }
+
+// EncoderBE is implemented by types that encode themselves to a big-endian encoder.
+type EncoderBE interface{ EncodeBE(e *EncBE) }
+
+// Union maps tag values to payload types, for messages made of a tag followed by
+// one of several payload layouts:
+//
+//	var messages = bitflux.NewUnion(1, false)
+//
+//	func init() {
+//		messages.Register(1, func() any { return new(Ping) })
+//		messages.Register(2, func() any { return new(Data) })
+//	}
+//
+// Payloads are decoded with DecodeLE or DecodeBE if they implement DecoderLE or
+// DecoderBE, and otherwise with UnmarshalBinary, which is given the remaining
+// bytes of the decoder; use a decoder bounded to one frame for those, such as
+// the one returned by LengthReader.NextDecLE. Encoding works the same way with
+// EncoderLE, EncoderBE and encoding.BinaryMarshaler.
+type Union struct {
+	Width     int  // Tag width in bytes: 1, 2, 4 or 8
+	BigEndian bool // Byte order of the tag, independent of the payload
+
+	ctors map[uint64]func() any
+	tags  map[reflect.Type]uint64
+}
+
+// NewUnion creates a union whose tags are width bytes wide.
+// It panics if width is not 1, 2, 4 or 8.
+func NewUnion(width int, bigEndian bool) *Union {
+	switch width {
+	case 1, 2, 4, 8:
+	default:
+		panic("bitflux: invalid union tag width " + strconv.Itoa(width))
+	}
+	return &Union{
+		Width:     width,
+		BigEndian: bigEndian,
+		ctors:     make(map[uint64]func() any),
+		tags:      make(map[reflect.Type]uint64),
+	}
+}
+
+// Register associates tag with the type of the values returned by ctor, which
+// is called for every decoded payload. It panics if tag does not fit in Width
+// bytes, or if tag or the type is already registered.
+func (u *Union) Register(tag uint64, ctor func() any) {
+	if u.Width < 8 && tag>>(8*u.Width) != 0 {
+		panic("bitflux: union tag " + strconv.FormatUint(tag, 10) + " overflows width " + strconv.Itoa(u.Width))
+	}
+	if _, dup := u.ctors[tag]; dup {
+		panic("bitflux: duplicate union tag " + strconv.FormatUint(tag, 10))
+	}
+	t := reflect.TypeOf(ctor())
+	if t == nil {
+		panic("bitflux: union constructor for tag " + strconv.FormatUint(tag, 10) + " returned nil")
+	}
+	if _, dup := u.tags[t]; dup {
+		panic("bitflux: duplicate union type " + t.String())
+	}
+	u.ctors[tag] = ctor
+	u.tags[t] = tag
+}
+
+// Tag returns the tag registered for the type of v.
+// Values are also found through a pointer to their type, so Ping matches a registered *Ping.
+func (u *Union) Tag(v any) (uint64, bool) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return 0, false
+	}
+	if tag, ok := u.tags[t]; ok {
+		return tag, true
+	}
+	if t.Kind() != reflect.Pointer {
+		tag, ok := u.tags[reflect.PointerTo(t)]
+		return tag, ok
+	}
+	return 0, false
+}
+
+// New returns a new value for tag from its constructor.
+func (u *Union) New(tag uint64) (any, error) {
+	ctor, ok := u.ctors[tag]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownTag, tag)
+	}
+	return ctor(), nil
+}
+
+// encodable returns the tag registered for the type of v and the value to encode.
+// A value whose pointer type is registered is copied into a new pointer, so the
+// pointer's methods, such as EncodeLE, are found.
+func (u *Union) encodable(v any) (uint64, any, bool) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return 0, nil, false
+	}
+	if tag, ok := u.tags[t]; ok {
+		return tag, v, true
+	}
+	if t.Kind() != reflect.Pointer {
+		if tag, ok := u.tags[reflect.PointerTo(t)]; ok {
+			p := reflect.New(t)
+			p.Elem().Set(reflect.ValueOf(v))
+			return tag, p.Interface(), true
+		}
+	}
+	return 0, nil, false
+}
+
+// tag assembles a tag from the first Width bytes of b.
+func (u *Union) tag(b []byte) uint64 {
+	var v uint64
+	for i := range b[:u.Width] {
+		if u.BigEndian {
+			v = v<<8 | uint64(b[i])
+		} else {
+			v |= uint64(b[i]) << (8 * i)
+		}
+	}
+	return v
+}
+
+// putTag stores tag in the first Width bytes of b and returns them.
+func (u *Union) putTag(b []byte, tag uint64) []byte {
+	b = b[:u.Width]
+	for i := range b {
+		if u.BigEndian {
+			b[len(b)-1-i] = byte(tag >> (8 * i))
+		} else {
+			b[i] = byte(tag >> (8 * i))
+		}
+	}
+	return b
+}
+
+// DecodeLE reads a tag and the matching payload from d and returns the payload.
+// It returns nil and sets d.Err on failure, to an error wrapping ErrUnknownTag for unregistered tags.
+func (u *Union) DecodeLE(d *DecLE) any {
+	b := d.scratch[:u.Width]
+	d.pull(b)
+	if d.Err != nil {
+		return nil
+	}
+	v, err := u.New(u.tag(b))
+	if err != nil {
+		d.Err = err
+		return nil
+	}
+	switch p := v.(type) {
+	case DecoderLE:
+		p.DecodeLE(d)
+	case encoding.BinaryUnmarshaler:
+		if b := d.ReadAll(); d.Err == nil {
+			d.Err = p.UnmarshalBinary(b)
+		}
+	default:
+		d.Err = fmt.Errorf("%w: %T cannot be decoded", ErrUnionType, v)
+	}
+	if d.Err != nil {
+		return nil
+	}
+	return v
+}
+
+// DecodeBE reads a tag and the matching payload from d and returns the payload.
+// It returns nil and sets d.Err on failure, to an error wrapping ErrUnknownTag for unregistered tags.
+func (u *Union) DecodeBE(d *DecBE) any {
+	b := d.scratch[:u.Width]
+	d.pull(b)
+	if d.Err != nil {
+		return nil
+	}
+	v, err := u.New(u.tag(b))
+	if err != nil {
+		d.Err = err
+		return nil
+	}
+	switch p := v.(type) {
+	case DecoderBE:
+		p.DecodeBE(d)
+	case encoding.BinaryUnmarshaler:
+		if b := d.ReadAll(); d.Err == nil {
+			d.Err = p.UnmarshalBinary(b)
+		}
+	default:
+		d.Err = fmt.Errorf("%w: %T cannot be decoded", ErrUnionType, v)
+	}
+	if d.Err != nil {
+		return nil
+	}
+	return v
+}
+
+// EncodeLE writes the tag registered for the type of v followed by v to e.
+// Nothing is written and e.Err is set to an error wrapping ErrUnionType if the type
+// is not registered or cannot be encoded. Wrap the call in e.Begin and e.Commit to
+// also hold back the tag when encoding the payload fails.
+func (u *Union) EncodeLE(e *EncLE, v any) {
+	if e.Err != nil {
+		return
+	}
+	tag, x, ok := u.encodable(v)
+	if !ok {
+		e.Err = fmt.Errorf("%w: %T is not registered", ErrUnionType, v)
+		return
+	}
+	switch p := x.(type) {
+	case EncoderLE:
+		e.push(u.putTag(e.scratch[:], tag))
+		p.EncodeLE(e)
+	case encoding.BinaryMarshaler:
+		e.push(u.putTag(e.scratch[:], tag))
+		e.Marshal(p)
+	default:
+		e.Err = fmt.Errorf("%w: %T cannot be encoded", ErrUnionType, v)
+	}
+}
+
+// EncodeBE writes the tag registered for the type of v followed by v to e.
+// Nothing is written and e.Err is set to an error wrapping ErrUnionType if the type
+// is not registered or cannot be encoded. Wrap the call in e.Begin and e.Commit to
+// also hold back the tag when encoding the payload fails.
+func (u *Union) EncodeBE(e *EncBE, v any) {
+	if e.Err != nil {
+		return
+	}
+	tag, x, ok := u.encodable(v)
+	if !ok {
+		e.Err = fmt.Errorf("%w: %T is not registered", ErrUnionType, v)
+		return
+	}
+	switch p := x.(type) {
+	case EncoderBE:
+		e.push(u.putTag(e.scratch[:], tag))
+		p.EncodeBE(e)
+	case encoding.BinaryMarshaler:
+		e.push(u.putTag(e.scratch[:], tag))
+		e.Marshal(p)
+	default:
+		e.Err = fmt.Errorf("%w: %T cannot be encoded", ErrUnionType, v)
+	}
+}
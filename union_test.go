@@ -0,0 +1,115 @@
+package bitflux
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type unionPing struct{ Seq uint16 }
+
+func (p *unionPing) DecodeLE(d *DecLE) { p.Seq = d.U16() }
+func (p *unionPing) EncodeLE(e *EncLE) { e.U16(p.Seq) }
+func (p *unionPing) DecodeBE(d *DecBE) { p.Seq = d.U16() }
+func (p *unionPing) EncodeBE(e *EncBE) { e.U16(p.Seq) }
+
+// unionText only implements the encoding.Binary interfaces.
+type unionText struct{ S string }
+
+func (t *unionText) UnmarshalBinary(b []byte) error { t.S = string(b); return nil }
+func (t unionText) MarshalBinary() ([]byte, error)  { return []byte(t.S), nil }
+
+func newTestUnion(width int, bigEndian bool) *Union {
+	u := NewUnion(width, bigEndian)
+	u.Register(0x01, func() any { return new(unionPing) })
+	u.Register(0x0102, func() any { return new(unionText) })
+	return u
+}
+
+func TestUnionRoundTrip(t *testing.T) {
+	u := newTestUnion(2, true)
+
+	var buf bytes.Buffer
+	enc := NewEncLE(&buf)
+	u.EncodeLE(enc, &unionPing{Seq: 0x0A0B})
+	if enc.Err != nil {
+		t.Fatalf("unexpected error: %v", enc.Err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{0x00, 0x01, 0x0B, 0x0A}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+	dec := NewDecLE(&buf)
+	if p, _ := u.DecodeLE(dec).(*unionPing); p == nil || p.Seq != 0x0A0B || dec.Err != nil {
+		t.Fatalf("got=%#v err=%v", p, dec.Err)
+	}
+
+	// Values match the registered pointer type; payloads without a codec
+	// method use the remaining bytes.
+	enc = NewEncLE(&buf)
+	u.EncodeLE(enc, unionText{S: "hi"})
+	if !bytes.Equal(buf.Bytes(), []byte{0x01, 0x02, 'h', 'i'}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+	decBE := NewDecBE(&buf)
+	if p, _ := u.DecodeBE(decBE).(*unionText); p == nil || p.S != "hi" {
+		t.Fatalf("got=%#v err=%v", p, decBE.Err)
+	}
+
+	// Values also find the codec methods of the registered pointer type.
+	enc = NewEncLE(&buf)
+	u.EncodeLE(enc, unionPing{Seq: 0x0C0D})
+	encBE := NewEncBE(&buf)
+	u.EncodeBE(encBE, unionPing{Seq: 0x0C0D})
+	if enc.Err != nil || encBE.Err != nil {
+		t.Fatalf("unexpected error: %v, %v", enc.Err, encBE.Err)
+	}
+	if !bytes.Equal(buf.Bytes(), []byte{0x00, 0x01, 0x0D, 0x0C, 0x00, 0x01, 0x0C, 0x0D}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+}
+
+func TestUnionTagOrder(t *testing.T) {
+	u := newTestUnion(4, false)
+	var buf bytes.Buffer
+	enc := NewEncBE(&buf)
+	u.EncodeBE(enc, &unionPing{Seq: 0x0A0B})
+	if !bytes.Equal(buf.Bytes(), []byte{0x01, 0x00, 0x00, 0x00, 0x0A, 0x0B}) {
+		t.Fatalf("got=% x", buf.Bytes())
+	}
+	if tag, ok := u.Tag(&unionText{}); !ok || tag != 0x0102 {
+		t.Fatalf("Tag: got=%#x ok=%v", tag, ok)
+	}
+}
+
+func TestUnionErrors(t *testing.T) {
+	u := NewUnion(1, false)
+	u.Register(0x01, func() any { return new(unionPing) })
+
+	dec := NewDecLE(bytes.NewReader([]byte{0x09, 0x00}))
+	if v := u.DecodeLE(dec); v != nil || !errors.Is(dec.Err, ErrUnknownTag) {
+		t.Fatalf("got=%v err=%v, want ErrUnknownTag", v, dec.Err)
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncLE(&buf)
+	u.EncodeLE(enc, struct{}{})
+	if !errors.Is(enc.Err, ErrUnionType) || buf.Len() != 0 {
+		t.Fatalf("got err=%v len=%d, want ErrUnionType and no output", enc.Err, buf.Len())
+	}
+
+	for name, f := range map[string]func(){
+		"width":    func() { NewUnion(3, false) },
+		"overflow": func() { u.Register(0x100, func() any { return new(int) }) },
+		"tag":      func() { u.Register(0x01, func() any { return new(int) }) },
+		"type":     func() { u.Register(0x03, func() any { return new(unionPing) }) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}